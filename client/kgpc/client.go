@@ -21,11 +21,15 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"sync"
 	"sync/atomic"
+
+	"go-kgp"
+	"go-kgp/msg"
 )
 
 // Client wraps a network connection into a player
@@ -33,6 +37,26 @@ type Client struct {
 	rwc  io.ReadWriteCloser
 	rid  uint64
 	lock sync.Mutex
+
+	// Persistent engine, if kgpc was started in engine mode
+	engine *engine
+	// Context for the next state request
+	pos position
+	// Games played during this session
+	summary *summary
+
+	greeted bool // did the server greet the client
+	failed  bool // did the server report an error
+}
+
+// Error returned by Handle if the server ended the session
+var errGoodbye = errors.New("Server said goodbye")
+
+// Propose MOVE for the state request ID on BOARD
+func (cli *Client) Move(id uint64, board *kgp.Board, move uint) {
+	cli.summary.move(id, board, move)
+	// Pits are counted starting with 1 in KGP
+	cli.Respond(id, "move", move+1)
 }
 
 // Send forwards an unreferenced message to the client
@@ -45,31 +69,11 @@ func (cli *Client) Error(to uint64, args ...interface{}) {
 }
 
 // Respond forwards a referenced message to the client
+//
+// The arguments are encoded as described by msg.Make.
 func (cli *Client) Respond(to uint64, command string, args ...interface{}) uint64 {
 	id := atomic.AddUint64(&cli.rid, 2)
-
-	fmt.Fprint(cli.rwc, id)
-	if to > 0 {
-		fmt.Fprintf(cli.rwc, "@%d", to)
-	}
-
-	fmt.Fprintf(cli.rwc, " %s", command)
-
-	for _, arg := range args {
-		fmt.Fprint(cli.rwc, " ")
-		switch arg.(type) {
-		case string:
-			fmt.Fprintf(cli.rwc, "%#v", arg)
-		case int:
-			fmt.Fprintf(cli.rwc, "%d", arg)
-		case float64:
-			fmt.Fprintf(cli.rwc, "%f", arg)
-		case *Board:
-			fmt.Fprint(cli.rwc, arg)
-		default:
-			panic("Unsupported type")
-		}
-	}
+	line := msg.Make(id, to, command, args...).String()
 
 	// attempt to send this message before any other message is sent
 	defer cli.lock.Unlock()
@@ -79,14 +83,25 @@ func (cli *Client) Respond(to uint64, command string, args ...interface{}) uint6
 		return 0
 	}
 
-	fmt.Fprint(cli.rwc, "\r\n")
+	_, err := fmt.Fprint(cli.rwc, line, "\r\n")
+	if err != nil {
+		log.Println(err)
+		return 0
+	}
 
 	return id
 }
 
 // Handle controls a connection and reads user input
-func (cli *Client) Handle() {
+//
+// The error indicates why the connection has ended.
+func (cli *Client) Handle() (err error) {
 	cli.rid = 1
+	cli.pos = position{clock: -1, opclock: -1}
+	cli.summary = makeSummary()
+	if cli.engine != nil {
+		cli.engine.attach(cli)
+	}
 
 	// Ensure that the client has a channel that is being
 	// communicated upon.
@@ -96,14 +111,36 @@ func (cli *Client) Handle() {
 	defer cli.rwc.Close()
 
 	scanner := bufio.NewScanner(cli.rwc)
+	scanner.Split(msg.SplitCommands())
 	for scanner.Scan() {
 		input := scanner.Text()
-		err := cli.Interpret(input)
-		if err != nil {
+		err = cli.Interpret(input)
+		if err == errGoodbye {
+			break
+		} else if err != nil {
 			log.Println(err)
+			err = nil
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+		if err == nil {
+			err = io.EOF
 		}
 	}
 
 	// Try to send a goodbye message, ignoring any errors
+	cli.lock.Lock()
 	fmt.Fprintf(cli.rwc, "goodbye\r\n")
+	cli.rwc = nil
+	cli.lock.Unlock()
+
+	// Requests from this session will not be answered any more
+	if cli.engine != nil {
+		cli.engine.attach(nil)
+	} else {
+		stopAll()
+	}
+
+	return err
 }
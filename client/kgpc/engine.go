@@ -0,0 +1,319 @@
+// Persistent Engine Handling
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of kgpc.
+//
+// kgpc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// kgpc is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with kgpc. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+// In engine mode, a single engine process is started and kept
+// running for as long as kgpc is connected.  Communication happens
+// over a line-based protocol, as documented in the README.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-kgp"
+)
+
+// Time an engine has to terminate after being asked to quit
+const quitGrace = 2 * time.Second
+
+// Context of a state request, as set by the server
+type position struct {
+	game    string // game:id, or an empty string if unknown
+	clock   int64  // time:clock, or -1 if unknown
+	opclock int64  // time:opclock, or -1 if unknown
+}
+
+// An engine is a long-lived process handling multiple requests
+type engine struct {
+	args []string
+	cli  *Client
+
+	lock    sync.Mutex
+	proc    *process
+	done    chan struct{}         // closed when the engine terminated
+	wake    chan struct{}         // notifies feed about new lines
+	queue   []string              // lines that have not been written yet
+	eof     bool                  // close the input after the queue
+	game    string                // last game announced to the engine
+	pending map[uint64]*kgp.Board // requests the engine is working on
+}
+
+// Encode an opaque string as a single word
+func word(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Join(strings.Fields(s), "_")
+}
+
+// Encode a clock value, where negative values are unknown
+func clock(t int64) string {
+	if t < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(t, 10)
+}
+
+// Launch the engine process, if it is not running (requires lock)
+func (e *engine) launch() error {
+	if e.proc != nil {
+		return nil
+	}
+
+	cmd, cleanup, err := command(e.args, "engine")
+	if err != nil {
+		return err
+	}
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		cleanup()
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanup()
+		return err
+	}
+	proc, err := launch(cmd)
+	if err != nil {
+		cleanup()
+		return err
+	}
+
+	e.proc, e.game = proc, ""
+	e.done = make(chan struct{})
+	e.wake = make(chan struct{}, 1)
+	e.queue, e.eof = nil, false
+	go e.read(proc, out, e.done, cleanup)
+	go e.feed(in, e.wake, e.done)
+	return nil
+}
+
+// Send a line to the engine (requires lock)
+//
+// The line is only queued, so that the lock is not held while the
+// engine is not reading its input.
+func (e *engine) send(format string, args ...interface{}) {
+	if e.wake == nil {
+		return
+	}
+	e.queue = append(e.queue, fmt.Sprintf(format, args...))
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Write queued lines to the engine, until it terminates
+func (e *engine) feed(in io.WriteCloser, wake, done chan struct{}) {
+	defer in.Close()
+	for {
+		select {
+		case <-wake:
+		case <-done:
+			return
+		}
+
+		e.lock.Lock()
+		if e.wake != wake {
+			// The engine has been restarted
+			e.lock.Unlock()
+			return
+		}
+		lines, eof := e.queue, e.eof
+		e.queue = nil
+		e.lock.Unlock()
+
+		for _, line := range lines {
+			_, err := fmt.Fprintln(in, line)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to write to engine:", err)
+				return
+			}
+		}
+		if eof {
+			return
+		}
+	}
+}
+
+// Process the output of the engine, until it terminates
+func (e *engine) read(proc *process, out io.Reader, done chan struct{}, cleanup func()) {
+	defer close(done)
+	defer cleanup()
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		e.interpret(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "reading input:", err)
+	}
+	err := proc.wait()
+	fmt.Fprintln(os.Stderr, "Engine terminated:", err)
+
+	// Yield all requests the engine will not respond to any more,
+	// and restart the engine with the next request.
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.proc == proc {
+		for id := range e.pending {
+			if e.cli != nil {
+				e.cli.Respond(id, "yield")
+			}
+			delete(e.pending, id)
+		}
+		e.proc, e.wake, e.queue = nil, nil, nil
+	}
+}
+
+// Handle a line sent by the engine
+func (e *engine) interpret(line string) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		if len(fields) > 0 {
+			fmt.Fprintf(os.Stderr, "Cannot parse \"%s\"\n", line)
+		}
+		return
+	}
+	id, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid request ID in \"%s\"\n", line)
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	board, ok := e.pending[id]
+	if !ok || e.cli == nil {
+		// The request has already been stopped
+		return
+	}
+
+	switch fields[0] {
+	case "move":
+		if len(fields) != 3 {
+			fmt.Fprintf(os.Stderr, "Cannot parse \"%s\"\n", line)
+			return
+		}
+		size, _ := board.Type()
+		move, err := strconv.Atoi(fields[2])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot parse \"%s\"\n", line)
+			return
+		}
+		if move < 0 || uint(move) >= size || !board.Legal(kgp.South, uint(move)) {
+			fmt.Fprintf(os.Stderr, "Attempted to make illegal move %d\n", move)
+			return
+		}
+		e.cli.Move(id, board, uint(move))
+	case "comment":
+		comment := strings.TrimSpace(line)
+		comment = strings.TrimSpace(strings.TrimPrefix(comment, fields[0]))
+		comment = strings.TrimSpace(strings.TrimPrefix(comment, fields[1]))
+		e.cli.Send("set", "info:comment", comment)
+	case "yield":
+		delete(e.pending, id)
+		e.cli.Respond(id, "yield")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command \"%s\"\n", fields[0])
+	}
+}
+
+// Forward the state request ID for BOARD to the engine
+func (e *engine) request(id uint64, board *kgp.Board, pos position) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if err := e.launch(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		e.cli.Respond(id, "yield")
+		return
+	}
+
+	if pos.game != "" && pos.game != e.game {
+		e.send("newgame %s", word(pos.game))
+		e.game = pos.game
+	}
+
+	var buf strings.Builder
+	size, _ := board.Type()
+	fmt.Fprintf(&buf, "position %d %s %s %s %d %d %d",
+		id, word(pos.game), clock(pos.clock), clock(pos.opclock),
+		size, board.Store(kgp.South), board.Store(kgp.North))
+	for i := uint(0); i < size; i++ {
+		fmt.Fprintf(&buf, " %d", board.Pit(kgp.South, i))
+	}
+	for i := uint(0); i < size; i++ {
+		fmt.Fprintf(&buf, " %d", board.Pit(kgp.North, i))
+	}
+	e.pending[id] = board
+	e.send("%s", buf.String())
+}
+
+// Forward responses to CLI, stopping all requests of the previous
+// client.  The engine keeps running between sessions.
+func (e *engine) attach(cli *Client) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for id := range e.pending {
+		e.send("stop %d", id)
+		delete(e.pending, id)
+	}
+	e.cli = cli
+}
+
+// Notify the engine that request ID has been stopped
+func (e *engine) stop(id uint64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if _, ok := e.pending[id]; ok {
+		delete(e.pending, id)
+		e.send("stop %d", id)
+	}
+}
+
+// Ask the engine to terminate, killing it after a grace period
+func (e *engine) quit() {
+	e.lock.Lock()
+	if e.wake == nil {
+		e.lock.Unlock()
+		return
+	}
+	e.send("quit")
+	e.eof = true
+	proc, done := e.proc, e.done
+	e.lock.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(quitGrace):
+		proc.kill()
+		<-done
+	}
+}
@@ -1,8 +1,7 @@
 module kgpc
 
-go 1.11
+go 1.16
 
-require (
-	github.com/gorilla/websocket v1.4.2 // indirect
-	nhooyr.io/websocket v1.8.7 // indirect
-)
+require go-kgp v0.0.0
+
+replace go-kgp => ../../server/go-kgp
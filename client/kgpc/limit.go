@@ -0,0 +1,141 @@
+// Resource Limits
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of kgpc.
+//
+// kgpc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// kgpc is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with kgpc. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Restrictions for all child processes
+var limit struct {
+	cpu    time.Duration // maximal CPU time, or 0
+	memory uint64        // maximal address space in bytes, or 0
+	grace  time.Duration // time between SIGTERM and SIGKILL
+	logdir string        // directory for the standard error, or ""
+}
+
+// Prepare a command to run ARGS
+//
+// If a log directory was given, the standard error of the process is
+// written to a file in the directory, with TAG in it's name.  The
+// returned function must be called after the process has terminated.
+func command(args []string, tag string) (*exec.Cmd, func(), error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	group(cmd)
+
+	if limit.logdir == "" {
+		cmd.Stderr = os.Stderr
+		return cmd, func() {}, nil
+	}
+
+	name := fmt.Sprintf("%s-%s.log", time.Now().Format("20060102-150405.000"), tag)
+	file, err := os.Create(filepath.Join(limit.logdir, name))
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.Stderr = file
+	return cmd, func() { file.Close() }, nil
+}
+
+// A child process, whose process group may be signalled until the
+// process has been reaped
+type process struct {
+	cmd    *exec.Cmd
+	lock   sync.Mutex
+	reaped bool        // has the process been waited for
+	timer  *time.Timer // pending kill, see terminate
+}
+
+// Start CMD with the resource limits applied
+//
+// The limits are applied before the agent is executed, so that
+// neither the agent nor the processes it forks can evade them.
+func launch(cmd *exec.Cmd) (*process, error) {
+	err := restrict(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to restrict process: %w", err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	return &process{cmd: cmd}, nil
+}
+
+// Send a signal to P using SIGNAL, unless it has been reaped
+func (p *process) signal(signal func(*os.Process)) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.reaped {
+		signal(p.cmd.Process)
+	}
+}
+
+// Kill the process group of P
+func (p *process) kill() { p.signal(kill) }
+
+// Ask P to terminate, and kill it after the grace period
+func (p *process) terminate() {
+	if limit.grace <= 0 {
+		p.kill()
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.reaped {
+		interrupt(p.cmd.Process)
+		p.timer = time.AfterFunc(limit.grace, p.kill)
+	}
+}
+
+// Wait for P to terminate, and kill processes it forked
+//
+// The process group is only killed while the process has not been
+// reaped, as its ID might otherwise have been reused.
+func (p *process) wait() error {
+	if !exited(p.cmd.Process) {
+		// Without process groups, only the process itself is
+		// signalled, which is safe after it has been reaped.
+		err := p.cmd.Wait()
+		p.release()
+		return err
+	}
+
+	// Kill processes forked by the agent that are still running
+	p.kill()
+	p.release()
+	return p.cmd.Wait()
+}
+
+// Prevent P from being signalled again
+func (p *process) release() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.reaped = true
+}
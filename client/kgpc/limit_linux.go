@@ -0,0 +1,161 @@
+// Resource Limits (Linux)
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of kgpc.
+//
+// kgpc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// kgpc is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with kgpc. If not, see
+// <http://www.gnu.org/licenses/>
+
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Resource limits can be applied on this system
+const rlimits = true
+
+// Start CMD in a new process group, so that all processes it forks
+// can be signalled at once
+func group(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Environment variable used to pass resource limits to a child
+const restrictEnv = "KGPC_RESTRICT"
+
+// Apply the resource limits to CMD
+//
+// Instead of the agent, kgpc is executed again, and replaces itself
+// with the agent after the limits have been applied (see init).
+// Processes forked by the agent inherit the limits.
+func restrict(cmd *exec.Cmd) error {
+	if limit.cpu <= 0 && limit.memory <= 0 {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	path, err := exec.LookPath(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	// The process receives SIGXCPU when the soft limit is reached,
+	// and is killed a second later.
+	sec := uint64((limit.cpu + time.Second - 1) / time.Second)
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, fmt.Sprintf("%s=%d %d", restrictEnv, sec, limit.memory))
+	cmd.Args = append([]string{self, path}, cmd.Args...)
+	cmd.Path = self
+	return nil
+}
+
+// Apply the resource limits passed by the parent and execute the agent
+//
+// This happens before the main function is invoked, as kgpc has been
+// started in place of the agent (see restrict).
+func init() {
+	spec, ok := os.LookupEnv(restrictEnv)
+	if !ok {
+		return
+	}
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "Failed to restrict process:", err)
+		os.Exit(1)
+	}
+
+	var cpu, memory uint64
+	_, err := fmt.Sscanf(spec, "%d %d", &cpu, &memory)
+	if err != nil {
+		fail(err)
+	}
+	if len(os.Args) < 3 {
+		fail(fmt.Errorf("missing command"))
+	}
+	if cpu > 0 {
+		err = syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{
+			Cur: cpu,
+			Max: cpu + 1,
+		})
+		if err != nil {
+			fail(err)
+		}
+	}
+	if memory > 0 {
+		err = syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{
+			Cur: memory,
+			Max: memory,
+		})
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	os.Unsetenv(restrictEnv)
+	fail(syscall.Exec(os.Args[1], os.Args[2:], os.Environ()))
+}
+
+// Block until PROC has exited, without reaping it
+//
+// As long as PROC has not been reaped, its ID cannot be reused, so
+// that the process group of PROC can be signalled safely.
+func exited(proc *os.Process) bool {
+	const P_PID = 1
+	var info [128]byte // siginfo_t
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, P_PID,
+			uintptr(proc.Pid), uintptr(unsafe.Pointer(&info)),
+			syscall.WEXITED|syscall.WNOWAIT, 0, 0)
+		switch errno {
+		case 0:
+			return true
+		case syscall.EINTR:
+			continue
+		default:
+			return false
+		}
+	}
+}
+
+// Send SIG to the process group of PROC
+func signalGroup(proc *os.Process, sig syscall.Signal) {
+	// The process group ID is the process ID of the leader
+	err := syscall.Kill(-proc.Pid, sig)
+	if err == syscall.ESRCH {
+		return
+	}
+	if err != nil {
+		proc.Signal(sig)
+	}
+}
+
+// Ask the process group of PROC to terminate
+func interrupt(proc *os.Process) { signalGroup(proc, syscall.SIGTERM) }
+
+// Kill the process group of PROC
+func kill(proc *os.Process) { signalGroup(proc, syscall.SIGKILL) }
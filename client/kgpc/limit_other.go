@@ -0,0 +1,46 @@
+// Resource Limits (Fallback)
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of kgpc.
+//
+// kgpc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// kgpc is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with kgpc. If not, see
+// <http://www.gnu.org/licenses/>
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Resource limits cannot be applied on this system
+const rlimits = false
+
+// Process groups are not supported on this system
+func group(*exec.Cmd) {}
+
+// Resource limits are not supported on this system (see main)
+func restrict(*exec.Cmd) error { return nil }
+
+// Processes cannot be waited for without reaping them
+func exited(*os.Process) bool { return false }
+
+// Ask PROC to terminate
+func interrupt(proc *os.Process) { proc.Signal(os.Interrupt) }
+
+// Kill PROC
+func kill(proc *os.Process) { proc.Kill() }
@@ -0,0 +1,165 @@
+// Local Practice Games
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of kgpc.
+//
+// kgpc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// kgpc is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with kgpc. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+// In local mode, kgpc plays a single game against a MinMax bot,
+// using the game implementation of the server.  The agent is
+// connected over an in-memory connection, so that no server or
+// network is required.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-kgp"
+	"go-kgp/bot"
+	"go-kgp/game"
+	"go-kgp/local"
+)
+
+// Options for a local game
+type practice struct {
+	opponent string        // MinMax bot, see bot.ParseMinMax
+	north    bool          // does the agent play north
+	size     uint          // number of pits per side
+	stones   uint          // number of stones per pit
+	timeout  time.Duration // time the agent has per move
+}
+
+// Draw BOARD, with the south side at the bottom
+func draw(w io.Writer, board *kgp.Board) {
+	size, _ := board.Type()
+
+	// North's pits are drawn from right to left, so that opposite
+	// pits are above one another
+	fmt.Fprint(w, "     ")
+	for i := size; i > 0; i-- {
+		fmt.Fprintf(w, " %3d ", i)
+	}
+	fmt.Fprint(w, "\n     ")
+	for i := size; i > 0; i-- {
+		fmt.Fprintf(w, "[%3d]", board.Pit(kgp.North, i-1))
+	}
+	fmt.Fprintf(w, "\n[%3d]%s[%3d]\n     ",
+		board.Store(kgp.North),
+		strings.Repeat(" ", 5*int(size)),
+		board.Store(kgp.South))
+	for i := uint(0); i < size; i++ {
+		fmt.Fprintf(w, "[%3d]", board.Pit(kgp.South, i))
+	}
+	fmt.Fprint(w, "\n     ")
+	for i := uint(1); i <= size; i++ {
+		fmt.Fprintf(w, " %3d ", i)
+	}
+	fmt.Fprintln(w)
+}
+
+// Play a game against a bot, printing every move to W
+func (p *practice) play(ctx context.Context, w io.Writer, eng *engine) error {
+	depth, eval, err := bot.ParseMinMax(p.opponent)
+	if err != nil {
+		return fmt.Errorf("Unknown opponent %q: %w", p.opponent, err)
+	}
+	opponent := bot.MakeMinMaxEval(mancala, depth, eval)
+
+	name := func(a kgp.Agent) string {
+		if a == opponent {
+			return opponent.User().Name
+		}
+		return "Agent"
+	}
+
+	count := 0
+	c := local.Configure(p.timeout, false, func(m *kgp.Move) {
+		count++
+		side := m.Game.Side(m.Agent)
+		fmt.Fprintf(w, "\nMove %d: %s (%s) sowed pit %d",
+			count, name(m.Agent), side, m.Choice+1)
+		switch m.Source {
+		case kgp.AUTOMATIC:
+			fmt.Fprint(w, " (the only legal move)")
+		case kgp.FALLBACK:
+			fmt.Fprint(w, " (random move, no decision in time)")
+		default:
+			if m.Comment != "" {
+				fmt.Fprintf(w, " (%s)", m.Comment)
+			}
+		}
+		fmt.Fprintf(w, " after %s\n", m.Think.Round(time.Millisecond))
+		draw(w, m.State)
+	})
+
+	// Connect the agent using the regular protocol implementation
+	conn, wait := local.Connect(c)
+	cli := &Client{rwc: conn, engine: eng}
+	done := make(chan struct{})
+	go func() {
+		cli.Handle()
+		close(done)
+	}()
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+
+	agent, err := wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	board := kgp.MakeBoard(p.size, p.stones)
+	if mancala == kgp.OWARE {
+		board = kgp.MakeOware()
+	}
+	g := &kgp.Game{
+		Board: board,
+		Id:    1,
+		South: agent,
+		North: opponent,
+	}
+	if p.north {
+		g.South, g.North = g.North, g.South
+	}
+	fmt.Fprintf(w, "%s (South) vs. %s (North)\n", name(g.South), name(g.North))
+	draw(w, g.Board)
+
+	game.Play(ctx, g, c)
+
+	fmt.Fprintln(w)
+	switch g.State {
+	case kgp.SOUTH_WON:
+		fmt.Fprintf(w, "%s (South) won", name(g.South))
+	case kgp.NORTH_WON:
+		fmt.Fprintf(w, "%s (North) won", name(g.North))
+	case kgp.SOUTH_RESIGNED:
+		fmt.Fprintf(w, "%s (South) resigned", name(g.South))
+	case kgp.NORTH_RESIGNED:
+		fmt.Fprintf(w, "%s (North) resigned", name(g.North))
+	case kgp.UNDECIDED:
+		fmt.Fprint(w, "Draw")
+	default:
+		fmt.Fprint(w, "The game was aborted")
+	}
+	fmt.Fprintf(w, " (%d:%d)\n", g.Board.Store(kgp.South), g.Board.Store(kgp.North))
+	return nil
+}
@@ -21,49 +21,229 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"net"
+	"io"
+	"log"
+	"math/rand"
 	"os"
-	"regexp"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	"nhooyr.io/websocket"
+	"go-kgp"
+	"go-kgp/client"
 )
 
 var (
 	token  = os.Getenv("TOKEN")
 	author = os.Getenv("AUTHOR")
 	name   = os.Getenv("NAME")
+
+	// Game to play, see -game
+	mancala kgp.Mancala
+
+	// Board requested from the server, if -size or -stones were
+	// given explicitly (zero means no preference)
+	reqSize, reqStones uint
 )
 
 func main() {
 	var (
-		cli  Client
-		err  error
+		eng  *engine
+		out  io.Writer = os.Stderr
 		dest string
+
+		// The current session, to summarise when interrupted
+		current *Client
+		n       uint
+		clock   sync.Mutex
 	)
 
-	if len(os.Args) <= 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [server address] [command ...]\n",
-			os.Args[0])
+	persistent := flag.Bool("engine", false,
+		"Start a single engine process and keep it running")
+	reconnect := flag.Bool("reconnect", true,
+		"Reconnect when the connection is lost")
+	forever := flag.Bool("forever", false,
+		"Reconnect even if the server ended the session")
+	minBackoff := flag.Duration("min-backoff", client.DEFAULT_MIN_BACKOFF,
+		"Initial delay before reconnecting")
+	maxBackoff := flag.Duration("max-backoff", client.DEFAULT_MAX_BACKOFF,
+		"Maximal delay before reconnecting")
+	summaryFile := flag.String("summary", "",
+		"Append session summaries to a file instead of stderr")
+	flag.DurationVar(&limit.cpu, "cpu", 0,
+		"Limit the CPU time of every process (Linux only)")
+	memory := flag.Uint64("memory", 0,
+		"Limit the memory of every process in MiB (Linux only)")
+	flag.DurationVar(&limit.grace, "grace", 0,
+		"Time a stopped process has to terminate before it is killed")
+	flag.StringVar(&limit.logdir, "stderr", "",
+		"Write the standard error of every process to a directory")
+	var prac practice
+	flag.StringVar(&prac.opponent, "local", "",
+		"Play a local game against a MinMax bot (e.g. \"mm6\")")
+	flag.BoolVar(&prac.north, "north", false,
+		"Play as north in a local game")
+	flag.UintVar(&prac.size, "size", 8,
+		"Number of pits per side in a local game, or to request from a server")
+	flag.UintVar(&prac.stones, "stones", 8,
+		"Number of stones per pit in a local game, or to request from a server")
+	flag.DurationVar(&prac.timeout, "time", 5*time.Second,
+		"Time the agent has per move in a local game")
+	game := flag.String("game", "kalah",
+		"Game to play (kalah, or oware which ignores -size and -stones)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [options] [server address] [command ...]\n"+
+				"       %s [options] -local [opponent] [command ...]\n",
+			os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if prac.opponent != "" {
+		agentArgs = flag.Args()
+	} else if flag.NArg() >= 2 {
+		dest = flag.Arg(0)
+		agentArgs = flag.Args()[1:]
+	}
+	if len(agentArgs) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var err error
+	mancala, err = kgp.ParseMancala(*game)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if prac.size == 0 {
+		fmt.Fprintln(os.Stderr, "The board must have at least one pit")
+		os.Exit(1)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "size":
+			reqSize = prac.size
+		case "stones":
+			reqStones = prac.stones
+		}
+	})
+	if *minBackoff <= 0 || *maxBackoff < *minBackoff {
+		fmt.Fprintln(os.Stderr, "Invalid backoff")
+		os.Exit(1)
+	}
+	limit.memory = *memory << 20
+	if (limit.cpu > 0 || limit.memory > 0) && !rlimits {
+		fmt.Fprintln(os.Stderr, "Resource limits are not supported on this system")
 		os.Exit(1)
 	}
+	if limit.logdir != "" {
+		err := os.MkdirAll(limit.logdir, 0755)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	rand.Seed(time.Now().UnixNano())
 
-	if ok, _ := regexp.MatchString(`^wss?://`, dest); ok {
-		ctx := context.Background()
-		c, _, err := websocket.Dial(ctx, dest, nil)
-		if err == nil {
-			cli.rwc = websocket.NetConn(ctx, c, websocket.MessageText)
+	if *summaryFile != "" {
+		file, err := os.OpenFile(*summaryFile,
+			os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *persistent {
+		eng = &engine{
+			args:    agentArgs,
+			pending: make(map[uint64]*kgp.Board),
 		}
-	} else {
-		if ok, _ := regexp.MatchString(`^:\d+$`, dest); !ok {
-			dest += ":2671"
+		eng.lock.Lock()
+		err := eng.launch()
+		eng.lock.Unlock()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		cli.rwc, err = net.Dial("tcp", dest)
 	}
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+
+	// Do not leave any processes running when interrupted, and
+	// summarise the current session
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		if eng != nil {
+			eng.quit()
+		} else {
+			stopAll()
+		}
+		clock.Lock()
+		if current != nil {
+			current.summary.write(out, n)
+		}
 		os.Exit(1)
+	}()
+
+	if prac.opponent != "" {
+		err := prac.play(context.Background(), os.Stdout, eng)
+		if eng != nil {
+			eng.quit()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	cli.Handle()
+	var attempt uint
+	for {
+		rwc, err := client.Dial(context.Background(), dest)
+		if err == nil {
+			cli := &Client{rwc: rwc, engine: eng}
+			clock.Lock()
+			current = cli
+			n++
+			clock.Unlock()
+
+			err = cli.Handle()
+
+			clock.Lock()
+			cli.summary.write(out, n)
+			current = nil
+			clock.Unlock()
+
+			if cli.greeted {
+				attempt = 0
+			}
+			// A server that says goodbye without reporting
+			// an error has deliberately ended the session.
+			if err == errGoodbye && !cli.failed && !*forever {
+				break
+			}
+		}
+		if !*reconnect && !*forever {
+			if err != nil && err != errGoodbye && err != io.EOF {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			break
+		}
+
+		wait := client.Backoff(attempt, *minBackoff, *maxBackoff)
+		log.Printf("Connection ended (%s), reconnecting in %s", err, wait)
+		attempt++
+		time.Sleep(wait)
+	}
+
+	if eng != nil {
+		eng.quit()
+	}
 }
@@ -21,24 +21,30 @@ package main
 
 import (
 	"bufio"
-	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
+	"sync"
+
+	"go-kgp"
+	"go-kgp/local"
 )
 
-var running map[uint64]*os.Process
+// Command line of the agent
+var agentArgs []string
 
-func start(cli *Client, id uint64, board *Board) {
-	_, ok := running[id]
-	if ok {
-		panic("Duplicate ID")
-	}
+var (
+	running = make(map[uint64]*process)
+	rlock   sync.Mutex
+)
 
-	args := flag.Args()
-	cmd := exec.Command(args[1], args[2:]...)
-	running[id] = cmd.Process
+func start(cli *Client, id uint64, board *kgp.Board) {
+	cmd, cleanup, err := command(agentArgs, strconv.FormatUint(id, 10))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	defer cleanup()
 
 	in, err := cmd.StdinPipe()
 	if err != nil {
@@ -50,9 +56,23 @@ func start(cli *Client, id uint64, board *Board) {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
-	cmd.Start()
+	proc, err := launch(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
 
+	rlock.Lock()
+	if _, ok := running[id]; ok {
+		panic("Duplicate ID")
+	}
+	running[id] = proc
+	rlock.Unlock()
+
+	size, _ := board.Type()
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		scanner := bufio.NewScanner(out)
 		scanner.Split(bufio.ScanWords)
 		for scanner.Scan() {
@@ -62,36 +82,49 @@ func start(cli *Client, id uint64, board *Board) {
 				fmt.Fprintf(os.Stderr, "Cannot parse \"%s\"\n", word)
 				continue
 			}
-			if !board.Legal(SideSouth, move) {
+			if move < 0 || uint(move) >= size || !board.Legal(kgp.South, uint(move)) {
 				fmt.Fprintf(os.Stderr, "Attempted to make illegal move %d\n", move)
 				continue
 			}
-			cli.Respond(id, "move", move)
+			cli.Move(id, board, uint(move))
 		}
 		if err := scanner.Err(); err != nil {
 			fmt.Fprintln(os.Stderr, "reading input:", err)
 		}
 	}()
 
-	fmt.Fprintf(in, "%d\n", len(board.northPits))
-	fmt.Fprintf(in, "%d\n%d\n", board.south, board.north)
-	for _, v := range board.southPits {
-		fmt.Fprintf(in, "%d\n", v)
-	}
-	for _, v := range board.northPits {
-		fmt.Fprintf(in, "%d\n", v)
-	}
+	local.WriteBoard(in, board)
+	in.Close()
 
-	cmd.Wait()
+	// All output has to be read before waiting for the process
+	<-done
+	proc.wait()
+	rlock.Lock()
+	delete(running, id)
+	rlock.Unlock()
 	cli.Respond(id, "yield")
 }
 
 func stop(id uint64) {
+	rlock.Lock()
+	defer rlock.Unlock()
+
 	proc, ok := running[id]
 	if !ok {
 		return
 	}
 
-	proc.Kill()
+	proc.terminate()
 	delete(running, id)
 }
+
+// Stop all running processes
+func stopAll() {
+	rlock.Lock()
+	defer rlock.Unlock()
+
+	for id, proc := range running {
+		proc.kill()
+		delete(running, id)
+	}
+}
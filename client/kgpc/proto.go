@@ -20,120 +20,36 @@
 package main
 
 import (
-	"errors"
-	"os"
-	"regexp"
+	"fmt"
+	"log"
 	"strconv"
 	"strings"
-	"unicode"
-)
 
-var (
-	parser = regexp.MustCompile(`^[[:space:]]*` +
-		`(?:([[:digit:]]+)(?:@([[:digit:]]+))?[[:space:]]+)?` +
-		`([[:alnum:]]+)(?:[[:space:]]+(.*))?` +
-		`[[:space:]]*$`)
-	errArgumentMismatch = errors.New("argument mismatch")
+	"go-kgp"
+	"go-kgp/msg"
 )
 
-// parse destructs RAW and tries to assign the parts to PARAMS
-func parse(raw string, params ...interface{}) error {
-	var (
-		inquotes bool
-		escape   bool
-		err      error
-
-		i   int
-		arg string
-	)
-
-	for i, arg = range strings.FieldsFunc(raw, func(c rune) bool {
-		if inquotes {
-			if escape {
-				escape = false
-				return false
-			} else if c == '"' {
-				inquotes = false
-				return true
-			} else {
-				escape = c == '\\'
-				return false
-			}
-		} else {
-			inquotes = c == '"'
-			return unicode.IsSpace(c) || inquotes
-		}
-	}) {
-		if i >= len(params) {
-			return errArgumentMismatch
-		}
-
-		switch param := params[i].(type) {
-		case *string:
-			*param = arg
-		case *uint64:
-			*param, err = strconv.ParseUint(arg, 10, 64)
-			if err != nil {
-				return err
-			}
-		case *int64:
-			*param, err = strconv.ParseInt(arg, 10, 64)
-			if err != nil {
-				return err
-			}
-		case *Board:
-			param = parseBoard(arg)
-			if param != nil {
-				return errors.New("malformed board")
-			}
-		}
-	}
-
-	if i+1 != len(params) {
-		return errArgumentMismatch
-	}
-
-	return nil
-}
-
 // Interpret parses and evaluates INPUT
 func (cli *Client) Interpret(input string) error {
-	matches := parser.FindStringSubmatch(input)
-	if matches == nil {
-		return errors.New("malformed input")
+	if strings.TrimSpace(input) == "" {
+		return nil
 	}
 
-	var (
-		id, ref uint64
-		err     error
-
-		cmd  = matches[3]
-		args = matches[4]
-	)
-	if matches[1] != "" {
-		id, err = strconv.ParseUint(matches[1], 10, 64)
-		if err != nil {
-			return nil
-		}
-	}
-	if matches[2] != "" {
-		ref, err = strconv.ParseUint(matches[2], 10, 64)
-		if err != nil {
-			return nil
-		}
+	cmd, err := msg.Lex(input)
+	if err != nil {
+		return err
 	}
 
-	switch cmd {
+	switch cmd.Name {
 	case "kgp":
-		var major, minor, patch int64
-		err = parse(args, &major, &minor, &patch)
+		v, err := msg.ParseVersion(cmd)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s (%s)", err, input)
 		}
-
-		if major != 1 {
-			return errors.New("unsupported version")
+		if v != msg.Current {
+			log.Printf("Server uses protocol version %s", v)
 		}
+
 		if token != "" {
 			cli.Send("set", "auth:token", token)
 		}
@@ -143,20 +59,68 @@ func (cli *Client) Interpret(input string) error {
 		if author != "" {
 			cli.Send("set", "info:author", author)
 		}
-		cli.Send("mode", "freeplay")
+		if reqSize != 0 {
+			cli.Send("set", "board:size", reqSize)
+		}
+		if reqStones != 0 {
+			cli.Send("set", "board:init", reqStones)
+		}
+		switch mancala {
+		case kgp.KALAH:
+			cli.Send("mode", "freeplay")
+		case kgp.OWARE:
+			cli.Send("mode", "oware")
+		}
+		cli.greeted = true
 	case "state":
-		var board *Board
-		err = parse(args, &board)
+		var board *kgp.Board
+		err = cmd.Scan(&board)
 		if err != nil {
+			cli.Error(cmd.Id, err.Error())
 			return err
 		}
-		start(cli, id, board)
+		board.SetMancala(mancala)
+		cli.summary.state(cmd.Id, cli.pos.game)
+		if cli.engine != nil {
+			cli.engine.request(cmd.Id, board, cli.pos)
+		} else {
+			go start(cli, cmd.Id, board)
+		}
 	case "stop":
-		stop(ref)
+		if cli.engine != nil {
+			cli.engine.stop(cmd.Ref)
+		} else {
+			stop(cmd.Ref)
+		}
+	case "set":
+		var key, val string
+		if err := cmd.Scan(&key, &val); err != nil {
+			return err
+		}
+		switch key {
+		case "game:id":
+			cli.pos.game = val
+		case "time:clock", "time:opclock":
+			t, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				t = -1
+			}
+			if key == "time:clock" {
+				cli.pos.clock = t
+			} else {
+				cli.pos.opclock = t
+			}
+		}
 	case "ping":
-		cli.Respond(id, "pong")
+		cli.Respond(cmd.Id, "pong")
+	case "error":
+		var reason string
+		if cmd.Scan(&reason) == nil {
+			log.Printf("Server reported an error: %s", reason)
+		}
+		cli.failed = true
 	case "goodbye":
-		os.Exit(0)
+		return errGoodbye
 	}
 
 	return nil
@@ -0,0 +1,152 @@
+// Session Summaries
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of kgpc.
+//
+// kgpc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// kgpc is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with kgpc. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go-kgp"
+)
+
+// A record of a game, as far as it was observed by kgpc
+type record struct {
+	id     string
+	states uint
+	board  *kgp.Board // state after the last proposed move
+}
+
+// The server does not announce the result of a game, so it is
+// inferred from the last state after the agent made a move.  The
+// result is only known if the game has ended or either side has
+// collected more than half of all stones.
+func (r *record) outcome() kgp.Outcome {
+	b := r.board
+	if b == nil {
+		return 0
+	}
+	if b.Over() {
+		return b.Outcome(kgp.South)
+	}
+
+	size, _ := b.Type()
+	total := b.Store(kgp.South) + b.Store(kgp.North)
+	for i := uint(0); i < size; i++ {
+		total += b.Pit(kgp.South, i) + b.Pit(kgp.North, i)
+	}
+	switch {
+	case 2*b.Store(kgp.South) > total:
+		return kgp.WIN
+	case 2*b.Store(kgp.North) > total:
+		return kgp.LOSS
+	}
+	return 0
+}
+
+// A summary of the games played during a session
+type summary struct {
+	lock      sync.Mutex
+	start     time.Time
+	anonymous uint               // state requests without a game
+	games     []*record          // games in the order they started
+	index     map[string]*record // games by ID
+	requests  map[uint64]*record // games by state request ID
+}
+
+func makeSummary() *summary {
+	return &summary{
+		start:    time.Now(),
+		index:    make(map[string]*record),
+		requests: make(map[uint64]*record),
+	}
+}
+
+// Note that the state request ID belongs to GAME
+func (s *summary) state(id uint64, game string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if game == "" {
+		s.anonymous++
+		return
+	}
+	r, ok := s.index[game]
+	if !ok {
+		r = &record{id: game}
+		s.index[game] = r
+		s.games = append(s.games, r)
+	}
+	r.states++
+	s.requests[id] = r
+}
+
+// Note that MOVE was proposed for the state request ID on BOARD
+func (s *summary) move(id uint64, board *kgp.Board, move uint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r, ok := s.requests[id]
+	if !ok {
+		return
+	}
+	r.board = board.Copy()
+	r.board.Sow(kgp.South, move)
+}
+
+// Write a summary of the session to W
+func (s *summary) write(w io.Writer, n uint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var won, lost, drawn uint
+	for _, r := range s.games {
+		switch r.outcome() {
+		case kgp.WIN:
+			won++
+		case kgp.LOSS:
+			lost++
+		case kgp.DRAW:
+			drawn++
+		}
+	}
+
+	end := time.Now()
+	fmt.Fprintf(w, "Session %d (%s to %s): %d games, %d won, %d lost, %d drawn, %d unknown\n",
+		n, s.start.Format(time.Stamp), end.Format(time.Stamp),
+		len(s.games), won, lost, drawn,
+		uint(len(s.games))-won-lost-drawn)
+	for _, r := range s.games {
+		result := "Unknown"
+		if o := r.outcome(); o != 0 {
+			result = o.String()
+		}
+		fmt.Fprintf(w, "\tGame %s: %s", r.id, result)
+		if r.board != nil {
+			fmt.Fprintf(w, " (%d:%d)",
+				r.board.Store(kgp.South), r.board.Store(kgp.North))
+		}
+		fmt.Fprintf(w, " after %d states\n", r.states)
+	}
+	if s.anonymous > 0 {
+		fmt.Fprintf(w, "\t%d states without a game\n", s.anonymous)
+	}
+}
@@ -17,15 +17,15 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
-	"net"
 	"os"
-	"regexp"
 	"strings"
 
-	"nhooyr.io/websocket"
+	"go-kgp/client"
+	"go-kgp/msg"
 )
 
 func main() {
@@ -41,52 +41,37 @@ func main() {
 		os.Exit(1)
 	}
 
-	if ok, _ := regexp.MatchString(`^wss?://`, dest); ok {
-		ctx := context.Background()
-		c, _, err := websocket.Dial(ctx, dest, nil)
-		if err == nil {
-			rwc = websocket.NetConn(ctx, c, websocket.MessageText)
-		}
-	} else {
-		if ok, _ := regexp.MatchString(`^:\d+$`, dest); !ok {
-			dest += ":2671"
-		}
-		rwc, err = net.Dial("tcp", dest)
-	}
+	dest = os.Args[1]
+	rwc, err = client.Dial(context.Background(), dest)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 	defer rwc.Close()
 
-	var line string
-	for {
-		_, err := fmt.Fscanln(rwc, &line)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return
-		}
-		if strings.TrimSpace(line) != "" {
+	scanner := bufio.NewScanner(rwc)
+	scanner.Split(msg.SplitCommands())
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
 			break
 		}
 	}
-
-	ok, err := regexp.MatchString(
-		`^\s*(?:\d*\s+)?kgp\s+1\s+\d+\s+\d\s*$`,
-		line)
-	if err != nil {
+	if err := scanner.Err(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
 
-	if !ok {
-		fmt.Fprintln(os.Stderr, "Unsupported KGP version")
+	cmd, err := msg.Lex(scanner.Text())
+	if err == nil {
+		_, err = msg.ParseVersion(cmd)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		return
 	}
 
-	r := strings.NewReplacer("\"", "\\\"", "\n", "\\n")
 	for _, tok := range os.Args[2:] {
-		fmt.Fprintf(rwc, `set auth:forget "%s"\r\n`, r.Replace(tok))
+		fmt.Fprintf(rwc, "%s\r\n", msg.Make(0, 0, "set", msg.Word("auth:forget"), tok))
 	}
-	fmt.Fprintln(rwc, "goodbye\r\n")
+	fmt.Fprint(rwc, "goodbye\r\n")
 }
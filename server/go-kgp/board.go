@@ -23,7 +23,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"math"
 	"math/rand"
 	"regexp"
 	"strconv"
@@ -42,8 +41,21 @@ type Board struct {
 	southPits []uint
 	// The initial board size
 	init uint
+	// The rules used when sowing and collecting
+	rules Rules
+	// The game played on the board
+	mancala Mancala
+	// Number of moves since the last capture (only for Oware)
+	idle uint
 }
 
+// Type returns the number of pits on each side and the number of
+// stones every pit held at the beginning of the game
+//
+// For parsed boards, the initial number of stones is derived from the
+// number of stones on the board, as these are conserved during a
+// game.  If the stones cannot be evenly distributed over the pits,
+// the initial number is 0.
 func (b *Board) Type() (size, init uint) {
 	return uint(len(b.northPits)), b.init
 }
@@ -60,6 +72,26 @@ func (b *Board) Pit(side Side, pit uint) uint {
 	}
 }
 
+// Rules returns the rules the board is played with
+func (b *Board) Rules() Rules {
+	return b.rules
+}
+
+// SetRules changes the rules the board is played with
+func (b *Board) SetRules(rules Rules) {
+	b.rules = rules
+}
+
+// Mancala returns the game that is played on the board
+func (b *Board) Mancala() Mancala {
+	return b.mancala
+}
+
+// SetMancala changes the game that is played on the board
+func (b *Board) SetMancala(mancala Mancala) {
+	b.mancala = mancala
+}
+
 func (b *Board) Store(side Side) uint {
 	if side {
 		return b.north
@@ -86,6 +118,9 @@ func MakeBoard(size, init uint) *Board {
 	return &board
 }
 
+// Parse a board in the KGP representation
+//
+// The board is not validated, see Validate.
 func Parse(spec string) (*Board, error) {
 	match := repr.FindStringSubmatch(spec)
 	if match == nil {
@@ -107,7 +142,16 @@ func Parse(spec string) (*Board, error) {
 		return nil, errors.New("invalid size")
 	}
 
-	b := MakeBoard(size, math.MaxUint)
+	var stones uint
+	for _, n := range data[1:] {
+		stones += n
+	}
+	var init uint
+	if stones%(2*size) == 0 {
+		init = stones / (2 * size)
+	}
+
+	b := MakeBoard(size, init)
 	b.south = data[1]
 	b.north = data[2]
 	for i := uint(0); i < size; i++ {
@@ -125,6 +169,9 @@ func (b *Board) Mirror() *Board {
 		northPits: b.southPits,
 		southPits: b.northPits,
 		init:      b.init,
+		rules:     b.rules,
+		mancala:   b.mancala,
+		idle:      b.idle,
 	}
 }
 
@@ -149,6 +196,9 @@ func (b *Board) Legal(side Side, pit uint) bool {
 	if pit >= uint(len(b.northPits)) {
 		panic("Illegal access")
 	}
+	if b.mancala == OWARE {
+		return b.legalOware(side, pit)
+	}
 	if side == North {
 		return b.northPits[pit] > 0
 	}
@@ -166,8 +216,8 @@ func (b *Board) Moves(side Side) (count, last uint) {
 	return
 }
 
-// Random returns a random legal move for SIDE
-func (b *Board) Random(side Side) (move uint) {
+// Random returns a random legal move for SIDE, drawn from RNG
+func (b *Board) Random(side Side, rng *rand.Rand) (move uint) {
 	legal := make([]uint, 0, len(b.northPits))
 
 	for i := uint(0); i < uint(len(b.northPits)); i++ {
@@ -176,9 +226,9 @@ func (b *Board) Random(side Side) (move uint) {
 		}
 	}
 
-	// if len(legal) == true, rand.Intn panics.  This is ok, because
+	// if len(legal) == true, rng.Intn panics.  This is ok, because
 	// Random shouldn't be called when the game is already over.
-	return legal[rand.Intn(len(legal))]
+	return legal[rng.Intn(len(legal))]
 }
 
 // Sow modifies the board by sowing PIT for player SELF
@@ -186,6 +236,9 @@ func (b *Board) Sow(self Side, pit uint) bool {
 	if len(b.northPits) != len(b.southPits) {
 		panic("Illegal board")
 	}
+	if b.mancala == OWARE {
+		return b.sowOware(self, pit)
+	}
 
 	var (
 		stones uint
@@ -244,15 +297,29 @@ func (b *Board) Sow(self Side, pit uint) bool {
 
 		return true
 	} else if side == self && pos > 0 {
-		last := int(pos - 1)
-		if side == North && b.northPits[last] == 1 && b.southPits[size-1-last] > 0 {
-			b.north += b.southPits[size-1-last] + 1
-			b.southPits[size-1-last] = 0
-			b.northPits[last] = 0
-		} else if side == South && b.southPits[last] == 1 && b.northPits[size-1-last] > 0 {
-			b.south += b.northPits[size-1-last] + 1
-			b.northPits[size-1-last] = 0
-			b.southPits[last] = 0
+		var (
+			last  = int(pos - 1)
+			own   = b.southPits
+			other = b.northPits
+			store = &b.south
+		)
+		if side == North {
+			own, other, store = b.northPits, b.southPits, &b.north
+		}
+
+		// Capture the stones in the opposite pit, if the
+		// last stone landed in an empty pit
+		var capture bool
+		switch b.rules.Capture {
+		case CAPTURE_OPPOSITE:
+			capture = own[last] == 1 && other[size-1-last] > 0
+		case CAPTURE_ALWAYS:
+			capture = own[last] == 1
+		}
+		if capture {
+			*store += other[size-1-last] + 1
+			other[size-1-last] = 0
+			own[last] = 0
 		}
 	}
 
@@ -286,6 +353,10 @@ func (b *Board) OverFor(side Side) (bool, uint) {
 
 // Over returns true if the game is over for either side
 func (b *Board) Over() bool {
+	if b.mancala == OWARE {
+		return b.overOware()
+	}
+
 	var stones uint
 
 	for _, pit := range b.northPits {
@@ -297,7 +368,7 @@ func (b *Board) Over() bool {
 	stones += b.north
 	stones += b.south
 
-	if b.north > stones/2 || b.south > stones/2 {
+	if !b.rules.NoEarlyWin && (b.north > stones/2 || b.south > stones/2) {
 		return true
 	}
 
@@ -308,19 +379,18 @@ func (b *Board) Over() bool {
 
 // Calculate the outcome for SIDE
 func (b *Board) Outcome(side Side) Outcome {
-	var north, south uint
-
-	for _, pit := range b.northPits {
-		north += pit
-	}
-	for _, pit := range b.southPits {
-		south += pit
-	}
-
 	if !b.Over() {
 		panic("Cannot determine outcome of unfinished game")
 	}
+	return b.Adjudicate(side)
+}
 
+// Adjudicate the outcome for SIDE if the game ended on this board
+//
+// Every side is awarded the stones in its store and the stones that
+// it would collect at the end of a game, even if the game is not over.
+func (b *Board) Adjudicate(side Side) Outcome {
+	north, south := b.remaining()
 	north += b.north
 	south += b.south
 
@@ -342,21 +412,39 @@ func (b *Board) Outcome(side Side) Outcome {
 	}
 }
 
+// Determine how many stones each side collects at the end of a game
+func (b *Board) remaining() (north, south uint) {
+	for _, p := range b.northPits {
+		north += p
+	}
+	for _, p := range b.southPits {
+		south += p
+	}
+
+	if b.mancala == KALAH && b.rules.Remainder == REMAINDER_EMPTIER {
+		// The player who emptied their side receives the
+		// stones that remain on the other side
+		if north == 0 {
+			north, south = south, 0
+		} else if south == 0 {
+			north, south = 0, north
+		}
+	}
+
+	return
+}
+
 // Move all stones for SIDE to the Kalah on SIDE
 func (b *Board) Collect() {
-	var north, south uint
-
 	if !b.Over() {
 		panic("Stones may not be collected")
 	}
 
-	for i, p := range b.northPits {
-		north += p
+	north, south := b.remaining()
+	for i := range b.northPits {
 		b.northPits[i] = 0
 	}
-
-	for i, p := range b.southPits {
-		south += p
+	for i := range b.southPits {
 		b.southPits[i] = 0
 	}
 
@@ -380,5 +468,8 @@ func (b *Board) Copy() *Board {
 		northPits: north,
 		southPits: south,
 		init:      b.init,
+		rules:     b.rules,
+		mancala:   b.mancala,
+		idle:      b.idle,
 	}
 }
@@ -410,6 +410,30 @@ func TestOutcome(t *testing.T) {
 	}
 }
 
+func TestAdjudicate(t *testing.T) {
+	for i, test := range []struct {
+		board   string
+		outcome Outcome
+	}{
+		{"<3, 2,3, 1,0,3, 1,0,0>", WIN},
+		{"<3, 3,2, 1,0,0, 1,0,3>", LOSS},
+		{"<3, 0,0, 3,3,3, 3,3,3>", DRAW},
+		// The stones on the board outweigh the stores
+		{"<3, 4,0, 0,0,1, 2,2,2>", LOSS},
+	} {
+		board, err := Parse(test.board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if board.Over() {
+			t.Fatalf("(%d) The game on %s is already over", i, board)
+		}
+		if outcome := board.Adjudicate(South); outcome != test.outcome {
+			t.Errorf("(%d) Expected %d, got %d", i, test.outcome, outcome)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	for i, test := range []struct {
 		input  string
@@ -497,3 +521,32 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestType(t *testing.T) {
+	for i, test := range []struct {
+		input      string
+		size, init uint
+	}{
+		{input: "<3,0,0,3,3,3,3,3,3>", size: 3, init: 3},
+		{input: "<3,10,8,0,0,0,0,0,0>", size: 3, init: 3},
+		{input: "<6,20,17,1,0,2,3,0,4,0,0,1,0,0,0>", size: 6, init: 4},
+		{input: "<2,0,0,0,0,0,0>", size: 2, init: 0},
+		// The stones cannot be distributed evenly
+		{input: "<2,1,0,0,0,0,0>", size: 2, init: 0},
+	} {
+		b, err := Parse(test.input)
+		if err != nil {
+			t.Fatalf("(%d) Failed with %q", i, err)
+		}
+		size, init := b.Type()
+		if size != test.size || init != test.init {
+			t.Errorf("(%d) Expected (%d, %d), got (%d, %d)",
+				i, test.size, test.init, size, init)
+		}
+	}
+
+	size, init := MakeBoard(8, 6).Copy().Type()
+	if size != 8 || init != 6 {
+		t.Errorf("Expected (8, 6), got (%d, %d)", size, init)
+	}
+}
@@ -0,0 +1,203 @@
+// Evaluation Heuristics
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package bot
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go-kgp"
+)
+
+// Evaluator estimates how favourable a board is
+//
+// A search evaluates the boards at its maximal depth.  Finished games
+// are always decided by the stores.
+type Evaluator interface {
+	// Evaluate returns a value that is greater, the better
+	// BOARD is for SIDE
+	Evaluate(board *kgp.Board, side kgp.Side) int64
+	// String returns the name of the evaluation (see ParseEval)
+	String() string
+}
+
+// An evaluation that compares a feature of both sides
+type feature struct {
+	name  string
+	descr string
+	count func(*kgp.Board, kgp.Side) int64
+}
+
+func (f *feature) Evaluate(board *kgp.Board, side kgp.Side) int64 {
+	return f.count(board, side) - f.count(board, !side)
+}
+
+func (f *feature) String() string { return f.name }
+
+var (
+	// Difference between the stores
+	Stores Evaluator = &feature{"stores", "difference between the stores", func(b *kgp.Board, s kgp.Side) int64 {
+		return int64(b.Store(s))
+	}}
+
+	// Difference between the stones in the pits on each side
+	Stones Evaluator = &feature{"stones", "difference between the stones on each side", func(b *kgp.Board, s kgp.Side) int64 {
+		size, _ := b.Type()
+		var stones int64
+		for i := uint(0); i < size; i++ {
+			stones += int64(b.Pit(s, i))
+		}
+		return stones
+	}}
+
+	// Difference between the number of legal moves
+	Mobility Evaluator = &feature{"mobility", "difference between the number of legal moves", func(b *kgp.Board, s kgp.Side) int64 {
+		if b.Over() {
+			return 0
+		}
+		count, _ := b.Moves(s)
+		return int64(count)
+	}}
+
+	// Difference between the number of moves that end in the
+	// store of the player, who may then move again
+	ExtraMoves Evaluator = &feature{"extra", "difference between the number of moves that end in the store", func(b *kgp.Board, s kgp.Side) int64 {
+		if b.Mancala() != kgp.KALAH {
+			return 0
+		}
+		var extra int64
+		size, _ := b.Type()
+		for i := uint(0); i < size; i++ {
+			if n := b.Pit(s, i); n > 0 && n%(2*size+1) == size-i {
+				extra++
+			}
+		}
+		return extra
+	}}
+
+	// Difference between the largest number of stones that each
+	// side could capture with a single move
+	Captures Evaluator = &feature{"captures", "difference between the most stones each side could capture", func(b *kgp.Board, s kgp.Side) int64 {
+		if b.Over() {
+			return 0
+		}
+		var best int64
+		size, _ := b.Type()
+		for i := uint(0); i < size; i++ {
+			if !b.Legal(s, i) {
+				continue
+			}
+			// The stones that are sown into the store are
+			// not captured
+			var sown uint
+			if n := b.Pit(s, i); b.Mancala() == kgp.KALAH && n >= size-i {
+				sown = (n-(size-i))/(2*size+1) + 1
+			}
+			c := b.Copy()
+			c.Sow(s, i)
+			if c.Over() {
+				// The stones collected at the end of
+				// the game are not captured either
+				continue
+			}
+			if gain := int64(c.Store(s)-b.Store(s)) - int64(sown); gain > best {
+				best = gain
+			}
+		}
+		return best
+	}}
+)
+
+// The evaluations that are combined by a Weighted evaluation, in
+// the order of the weights
+var Features = []Evaluator{Stores, Stones, Mobility, ExtraMoves, Captures}
+
+// Weighted is a linear combination of the evaluations in Features
+type Weighted []float64
+
+func (w Weighted) Evaluate(board *kgp.Board, side kgp.Side) int64 {
+	var sum float64
+	for i, f := range Features {
+		if i < len(w) && w[i] != 0 {
+			sum += w[i] * float64(f.Evaluate(board, side))
+		}
+	}
+	return int64(math.Round(sum))
+}
+
+func (w Weighted) String() string {
+	weights := make([]string, len(w))
+	for i, weight := range w {
+		weights[i] = strconv.FormatFloat(weight, 'g', -1, 64)
+	}
+	return fmt.Sprintf("weighted(%s)", strings.Join(weights, ","))
+}
+
+// Describe the evaluation EVAL in a sentence
+func describe(eval Evaluator) string {
+	switch e := eval.(type) {
+	case *feature:
+		return e.descr
+	case Weighted:
+		var terms []string
+		for i, w := range e {
+			if i < len(Features) && w != 0 {
+				terms = append(terms, fmt.Sprintf("%s times the %s",
+					strconv.FormatFloat(w, 'g', -1, 64),
+					describe(Features[i])))
+			}
+		}
+		if len(terms) == 0 {
+			return "constant zero"
+		}
+		return "sum of " + strings.Join(terms, ", ")
+	default:
+		return eval.String()
+	}
+}
+
+// ParseEval returns the evaluation designated by NAME
+//
+// The name is either the name of one of the Features or "weighted",
+// in which case WEIGHTS must contain a weight for every feature.  An
+// empty name designates Stores.
+func ParseEval(name string, weights []float64) (Evaluator, error) {
+	if name == "weighted" {
+		if len(weights) != len(Features) {
+			return nil, fmt.Errorf("Expected %d weights, got %d",
+				len(Features), len(weights))
+		}
+		return Weighted(weights), nil
+	}
+	if len(weights) > 0 {
+		return nil, fmt.Errorf("Weights are only used by weighted evaluations")
+	}
+	if name == "" {
+		return Stores, nil
+	}
+	for _, f := range Features {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown evaluation %q", name)
+}
@@ -0,0 +1,125 @@
+// Evaluation Heuristics Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package bot
+
+import (
+	"testing"
+
+	"go-kgp"
+)
+
+func TestEvaluate(t *testing.T) {
+	for i, test := range []struct {
+		state string
+		eval  Evaluator
+		value int64
+	}{
+		{state: "<3, 5,2, 1,0,3, 2,2,3>", eval: Stores, value: 3},
+		{state: "<3, 5,2, 1,0,3, 2,2,3>", eval: Stones, value: -3},
+		{state: "<3, 5,2, 1,0,3, 0,2,0>", eval: Mobility, value: 1},
+		// South can move again from the first and third pit,
+		// north only from the second pit
+		{state: "<3, 0,0, 3,0,1, 0,2,0>", eval: ExtraMoves, value: 1},
+		// A move can also end in the store after a full round
+		{state: "<3, 0,0, 3,0,8, 0,2,8>", eval: ExtraMoves, value: 0},
+		// South can capture the two stones in north's first pit
+		// (and the capturing stone), north cannot capture
+		{state: "<3, 3,3, 2,5,0, 2,0,1>", eval: Captures, value: 3},
+		// Sowing into the store is not a capture
+		{state: "<3, 0,0, 0,0,4, 2,2,2>", eval: Captures, value: 0},
+		// Neither is collecting the stones at the end of the game
+		{state: "<3, 0,0, 1,0,5, 0,2,0>", eval: Captures, value: 0},
+		{state: "<3, 5,2, 1,0,3, 2,2,3>", eval: Weighted{1, 0.5, 0, 0, 0}, value: 2},
+		{state: "<3, 5,2, 1,0,3, 2,2,3>", eval: Weighted{2, 0, 0, 0, 0}, value: 6},
+	} {
+		board, err := kgp.Parse(test.state)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
+		if v := test.eval.Evaluate(board, kgp.South); v != test.value {
+			t.Errorf("(%d) Expected %s to be %d for south, got %d",
+				i, test.eval, test.value, v)
+		}
+		if v := test.eval.Evaluate(board, kgp.North); v != -test.value {
+			t.Errorf("(%d) Expected %s to be %d for north, got %d",
+				i, test.eval, -test.value, v)
+		}
+	}
+}
+
+func TestParseEval(t *testing.T) {
+	for _, f := range Features {
+		e, err := ParseEval(f.String(), nil)
+		if err != nil || e != f {
+			t.Errorf("Failed to parse %q", f)
+		}
+	}
+	if e, err := ParseEval("", nil); err != nil || e != Stores {
+		t.Error("Expected the empty name to designate stores")
+	}
+	e, err := ParseEval("weighted", []float64{1, 0, 0.5, 0, -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.String() != "weighted(1,0,0.5,0,-1)" {
+		t.Errorf("Unexpected name %s", e)
+	}
+	for _, test := range []struct {
+		name    string
+		weights []float64
+	}{
+		{name: "unknown"},
+		{name: "weighted"},
+		{name: "weighted", weights: []float64{1, 2}},
+		{name: "stores", weights: []float64{1}},
+	} {
+		if _, err := ParseEval(test.name, test.weights); err == nil {
+			t.Errorf("Expected %q with %v to fail", test.name, test.weights)
+		}
+	}
+}
+
+func TestSearchWith(t *testing.T) {
+	board := kgp.MakeBoard(6, 4)
+	for _, eval := range append(Features, Weighted{1, 0.5, 0.5, 1, 0.5}) {
+		move, _ := SearchWith(board, kgp.South, 4, eval)
+		if !board.Legal(kgp.South, move) {
+			t.Errorf("%s proposed the illegal move %d", eval, move)
+		}
+	}
+
+	// The evaluation of a finished game only depends on the stores
+	board, _ = kgp.Parse("<2, 0,0, 0,1, 0,0>")
+	if move, ev := SearchWith(board, kgp.South, 3, Weighted{2, 1, 1, 1, 1}); move != 1 || ev != decided {
+		t.Errorf("Expected move 1 with evaluation %d, got %d (%d)", decided, move, ev)
+	}
+
+	// Sowing the second pit wins the game, which has to be
+	// preferred over any estimate of the first pit
+	board, _ = kgp.Parse("<2, 2,0, 1,1, 1,0>")
+	for _, eval := range []Evaluator{Stones, Mobility, Weighted{0, 1, 1, 1, 1}} {
+		for depth := uint(1); depth <= 4; depth++ {
+			if move, ev := SearchWith(board, kgp.South, depth, eval); move != 1 || ev <= 0 {
+				t.Errorf("%s with depth %d did not take the win, got %d (%d)",
+					eval, depth, move, ev)
+			}
+		}
+	}
+}
@@ -20,23 +20,24 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"math"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"go-kgp"
 )
 
-// We need a random
-var nonce string = os.Getenv("NONCE")
-
 type minmax struct {
-	depth uint      // ply cutoff
-	user  *kgp.User // database entry
+	depth   uint        // ply cutoff
+	eval    Evaluator   // evaluation of the leaves
+	user    *kgp.User   // database entry
+	mancala kgp.Mancala // game the bot plays
 }
 
-func search(Σ *kgp.Board, π kgp.Side, Δ uint) (uint, int64) {
+func search(Σ *kgp.Board, π kgp.Side, Δ uint, ε Evaluator) (uint, int64) {
 	λ, _ := Σ.Type()
 	var it func(*kgp.Board, kgp.Side, uint, int64, int64) (uint, int64)
 
@@ -79,9 +80,10 @@ func search(Σ *kgp.Board, π kgp.Side, Δ uint) (uint, int64) {
 			var φ int64
 			if over := n.Over(); δ == 0 || over {
 				if over {
-					n.Collect()
+					φ = final(n, π, ε)
+				} else {
+					φ = ε.Evaluate(n, π)
 				}
-				φ = int64(n.Store(π)) - int64(n.Store(!π))
 			} else {
 				// NOTE: We are xor'ing the state with
 				// side-repetition flag.
@@ -118,11 +120,43 @@ func search(Σ *kgp.Board, π kgp.Side, Δ uint) (uint, int64) {
 	return it(Σ, π, Δ, math.MinInt, math.MaxInt)
 }
 
+// Finished games outweigh the evaluation of any unfinished game
+const decided int64 = 1 << 32
+
+// Evaluate the finished game on BOARD for SIDE
+//
+// A finished game is decided by the stores, no matter what evaluation
+// is used to estimate unfinished games.  Unless the evaluation is
+// Stores itself, the difference is scaled by decided, so that a
+// search prefers a forced win over any estimate.
+func final(board *kgp.Board, side kgp.Side, eval Evaluator) int64 {
+	board.Collect()
+	φ := Stores.Evaluate(board, side)
+	if eval != Stores {
+		φ *= decided
+	}
+	return φ
+}
+
+// Search for the best move for SIDE on BOARD, looking DEPTH plies ahead
+//
+// The second return value is the evaluation of the move, as the
+// difference between the stores of SIDE and the opponent.
+func Search(board *kgp.Board, side kgp.Side, depth uint) (uint, int64) {
+	return search(board, side, depth, Stores)
+}
+
+// SearchWith is like Search, but evaluates boards using EVAL
+func SearchWith(board *kgp.Board, side kgp.Side, depth uint, eval Evaluator) (uint, int64) {
+	return search(board, side, depth, eval)
+}
+
 func (m *minmax) Request(g *kgp.Game) (*kgp.Move, bool) {
 	if g.Board.Over() {
 		panic("Unexpected final state")
 	}
-	move, ev := search(g.Board, g.Side(m), m.depth)
+	start := time.Now()
+	move, ev := search(g.Board, g.Side(m), m.depth, m.eval)
 	if !g.Board.Legal(g.Side(m), move) {
 		panic(fmt.Sprintf("Proposing illegal move %d for %s given %s",
 			move, g.Side(m), g.Board))
@@ -131,31 +165,138 @@ func (m *minmax) Request(g *kgp.Game) (*kgp.Move, bool) {
 		Choice:  move,
 		Comment: fmt.Sprintf("Evaluation: %d", ev),
 		Agent:   m,
-		State:   g.Board,
 		Game:    g,
 		Stamp:   time.Now(),
+		Think:   time.Since(start),
 	}, false
 }
 
-func (m *minmax) User() *kgp.User { return m.user }
-func (m *minmax) String() string  { return fmt.Sprintf("MM%d", m.depth) }
-func (*minmax) IsBot()            {}
-func (*minmax) Alive() bool       { return true } // bots never die
+func (m *minmax) User() *kgp.User      { return m.user }
+func (*minmax) IsBot()                 {}
+func (m *minmax) Mancala() kgp.Mancala { return m.mancala }
+func (*minmax) Alive() bool            { return true } // bots never die
+
+func (m *minmax) String() string {
+	if m.eval == Stores {
+		return fmt.Sprintf("MM%d", m.depth)
+	}
+	return fmt.Sprintf("MM%d/%s", m.depth, m.eval)
+}
 
 func MakeMinMax(depth uint) kgp.Agent {
-	return &minmax{
-		user: &kgp.User{
-			Token: fmt.Sprintf("%s-mm%d", nonce,
-				depth),
-			Name: fmt.Sprintf("MinMax-%d", depth),
-			Descr: fmt.Sprintf(`
+	return MakeMinMaxFor(kgp.KALAH, depth)
+}
+
+// Create a MinMax bot that is scheduled for games of MANCALA
+//
+// Bots with the same depth share a database entry for all games.
+func MakeMinMaxFor(mancala kgp.Mancala, depth uint) kgp.Agent {
+	return MakeMinMaxEval(mancala, depth, Stores)
+}
+
+// Create a MinMax bot that evaluates boards using EVAL
+//
+// Bots with the same depth and evaluation share a database entry for
+// all games.
+func MakeMinMaxEval(mancala kgp.Mancala, depth uint, eval Evaluator) kgp.Agent {
+	name := fmt.Sprintf("MinMax-%d", depth)
+	if eval != Stores {
+		name = fmt.Sprintf("%s (%s)", name, eval)
+	}
+	return makeMinMax(mancala, depth, eval, &kgp.User{
+		Token: Token(name),
+		Name:  name,
+	})
+}
+
+// ErrNotMinMax is returned by ParseMinMax for other specifications
+var ErrNotMinMax = errors.New("Not a MinMax bot")
+
+// ParseMinMax parses the specification SPEC of a MinMax bot
+//
+// "mmN" denotes a bot with depth N, optionally followed by a colon and
+// an evaluation (e.g. "mm6:mobility").  The evaluation is either
+// understood by ParseEval or "weighted:" followed by comma-separated
+// weights.  If SPEC does not denote a MinMax bot, ErrNotMinMax is
+// returned.
+func ParseMinMax(spec string) (uint, Evaluator, error) {
+	if !strings.HasPrefix(spec, "mm") {
+		return 0, nil, ErrNotMinMax
+	}
+	parts := strings.SplitN(spec[2:], ":", 2)
+	depth, err := strconv.ParseUint(parts[0], 10, 0)
+	if err != nil {
+		return 0, nil, ErrNotMinMax
+	}
+	if len(parts) == 1 {
+		return uint(depth), Stores, nil
+	}
+
+	var (
+		name    = parts[1]
+		weights []float64
+	)
+	if strings.HasPrefix(name, "weighted:") {
+		for _, w := range strings.Split(name[len("weighted:"):], ",") {
+			f, err := strconv.ParseFloat(w, 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("Invalid weight %q", w)
+			}
+			weights = append(weights, f)
+		}
+		name = "weighted"
+	}
+	eval, err := ParseEval(name, weights)
+	if err != nil {
+		return 0, nil, err
+	}
+	return uint(depth), eval, nil
+}
+
+// Create a MinMax bot that is represented by USER
+func makeMinMax(mancala kgp.Mancala, depth uint, eval Evaluator, user *kgp.User) kgp.Agent {
+	if user.Descr == "" {
+		user.Descr = fmt.Sprintf(`
 Simple reference implementation for a MinMax agent.
 
 This agent is a bot and is provided by the practice server to make
 comparing the performanceeasier.  Note that bots are not time-bound
 and will always complete their search.  This agent will always search
-%d plies ahead and return the best move it can find.`, depth),
-		},
-		depth: depth,
+%d plies ahead and return the best move it can find, evaluating the
+boards it reaches by the %s.`, depth, describe(eval))
+	}
+	return &minmax{
+		user:    user,
+		depth:   depth,
+		eval:    eval,
+		mancala: mancala,
 	}
 }
+
+func init() {
+	Register(&Kind{
+		Name:  "minmax",
+		Descr: "Search a fixed number of plies ahead",
+		Params: []Param{
+			{Name: "depth", Type: PARAM_UINT, Descr: "number of plies to search"},
+			{Name: "eval", Type: PARAM_STRING, Default: "",
+				Descr: "evaluation of the boards (see ParseEval)"},
+			{Name: "weights", Type: PARAM_FLOATS, Default: []float64{},
+				Descr: "weights of a weighted evaluation"},
+		},
+		Title: func(p Params) string {
+			name := fmt.Sprintf("MinMax-%d", p.Uint("depth"))
+			if eval, err := ParseEval(p.Text("eval"), p.Floats("weights")); err == nil && eval != Stores {
+				name = fmt.Sprintf("%s (%s)", name, eval)
+			}
+			return name
+		},
+		Make: func(mancala kgp.Mancala, user *kgp.User, p Params) (kgp.Agent, error) {
+			eval, err := ParseEval(p.Text("eval"), p.Floats("weights"))
+			if err != nil {
+				return nil, err
+			}
+			return makeMinMax(mancala, p.Uint("depth"), eval, user), nil
+		},
+	})
+}
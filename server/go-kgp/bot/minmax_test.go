@@ -21,6 +21,7 @@ package bot
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"go-kgp"
@@ -94,7 +95,7 @@ func TestSearch(t *testing.T) {
 		}
 		size, _ := state.Type()
 
-		move, ev := search(state, test.side, test.depth)
+		move, ev := search(state, test.side, test.depth, Stores)
 		if move >= size {
 			t.Errorf("[%d] Proposed impossible move %d given %s (%d)",
 				i, move, state, ev)
@@ -117,8 +118,77 @@ func BenchmarkSearch(b *testing.B) {
 	for n := uint(2); n <= 8; n++ {
 		b.Run(fmt.Sprintf("search_%d", n), func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
-				search(board, kgp.South, n)
+				search(board, kgp.South, n, Stores)
 			}
 		})
 	}
 }
+
+func TestOpening(t *testing.T) {
+	for _, board := range []*kgp.Board{
+		kgp.MakeBoard(6, 4),
+		kgp.MakeBoard(8, 8),
+		kgp.MakeOware(),
+	} {
+		for i := int64(0); i < 10; i++ {
+			o := Opening(board, 4, 4, 2, rand.New(rand.NewSource(i)))
+			if o.Board.Over() {
+				t.Fatalf("Opening %s is over", o.Board)
+			}
+			if o.Board.Mancala() != board.Mancala() {
+				t.Errorf("Opening %s lost the game", o.Board)
+			}
+			size, init := board.Type()
+			if s, i := o.Board.Type(); s != size || i != init {
+				t.Errorf("Opening %s lost its type", o.Board)
+			}
+
+			// The evaluation must be reproducible
+			_, ev := search(o.Board, o.Current, 4, Stores)
+			if ev != o.Eval {
+				t.Errorf("Expected evaluation %d, got %d for %s",
+					o.Eval, ev, o.Board)
+			}
+			if o.Board.String() == board.String() {
+				t.Errorf("No moves were made on %s", o.Board)
+			}
+
+			// The same seed must generate the same opening
+			p := Opening(board, 4, 4, 2, rand.New(rand.NewSource(i)))
+			if p.Board.String() != o.Board.String() || p.Current != o.Current {
+				t.Errorf("Expected opening %s, got %s", o.Board, p.Board)
+			}
+		}
+	}
+}
+
+func TestParseMinMax(t *testing.T) {
+	for _, test := range []struct {
+		spec  string
+		depth uint
+		eval  string
+	}{
+		{"mm4", 4, "stores"},
+		{"mm6:mobility", 6, "mobility"},
+		{"mm2:weighted:1,0,0.5,0,-1", 2, "weighted(1,0,0.5,0,-1)"},
+	} {
+		depth, eval, err := ParseMinMax(test.spec)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.spec, err)
+			continue
+		}
+		if depth != test.depth || eval.String() != test.eval {
+			t.Errorf("Parsed %q as depth %d with %s", test.spec, depth, eval)
+		}
+	}
+	for _, spec := range []string{"./agent", "mm", "mmx", "mm-1"} {
+		if _, _, err := ParseMinMax(spec); err != ErrNotMinMax {
+			t.Errorf("Expected %q not to denote a MinMax bot, got %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"mm4:unknown", "mm4:weighted:1,x", "mm4:weighted:1"} {
+		if _, _, err := ParseMinMax(spec); err == nil || err == ErrNotMinMax {
+			t.Errorf("Expected %q to be rejected, got %v", spec, err)
+		}
+	}
+}
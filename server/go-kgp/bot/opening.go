@@ -0,0 +1,101 @@
+// Generation of balanced openings
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package bot
+
+import (
+	"math/rand"
+
+	"go-kgp"
+)
+
+// Number of random openings that are tried before giving up on
+// finding a balanced one
+const OPENING_ATTEMPTS = 64
+
+// Play PLIES random moves on a copy of BOARD, starting with south
+//
+// If the game ends before all moves were made, nil is returned.
+func playout(board *kgp.Board, plies uint, rng *rand.Rand) (*kgp.Board, kgp.Side) {
+	var (
+		b    = board.Copy()
+		side = kgp.South
+	)
+	for i := uint(0); i < plies; i++ {
+		if b.Over() {
+			return nil, side
+		}
+		if !b.Sow(side, b.Random(side, rng)) {
+			side = !side
+		}
+	}
+	if b.Over() {
+		return nil, side
+	}
+	return b, side
+}
+
+// Opening generates a balanced position to start a game from
+//
+// Starting from BOARD, PLIES random moves are drawn from RNG.  The
+// position is evaluated by searching DEPTH plies ahead and accepted
+// if the evaluation for the side to move is at most MARGIN stones.
+// If no balanced position is found after OPENING_ATTEMPTS tries, the
+// most balanced position is returned.
+func Opening(board *kgp.Board, plies, depth, margin uint, rng *rand.Rand) *kgp.Opening {
+	var best *kgp.Opening
+	for i := 0; i < OPENING_ATTEMPTS; i++ {
+		b, side := playout(board, plies, rng)
+		if b == nil {
+			continue
+		}
+
+		_, ev := search(b, side, depth, Stores)
+		if best == nil || abs(ev) < abs(best.Eval) {
+			best = &kgp.Opening{
+				Board:   b,
+				Current: side,
+				Plies:   plies,
+				Eval:    ev,
+			}
+		}
+		if abs(ev) <= int64(margin) {
+			break
+		}
+	}
+
+	if best == nil {
+		// Every playout ended the game, which can only happen
+		// on tiny boards
+		_, ev := search(board, kgp.South, depth, Stores)
+		best = &kgp.Opening{
+			Board:   board.Copy(),
+			Current: kgp.South,
+			Eval:    ev,
+		}
+	}
+	return best
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
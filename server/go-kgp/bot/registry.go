@@ -0,0 +1,229 @@
+// Registry of Bot Kinds
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"go-kgp"
+)
+
+// ParamType designates the type of the value of a parameter
+type ParamType uint8
+
+const (
+	// A non-negative integer (uint)
+	PARAM_UINT ParamType = iota
+	// A string
+	PARAM_STRING
+	// A list of numbers ([]float64)
+	PARAM_FLOATS
+)
+
+func (t ParamType) String() string {
+	switch t {
+	case PARAM_UINT:
+		return "a non-negative integer"
+	case PARAM_STRING:
+		return "a string"
+	case PARAM_FLOATS:
+		return "a list of numbers"
+	default:
+		panic(fmt.Sprintf("Illegal parameter type: %d", t))
+	}
+}
+
+// Param describes a parameter of a kind of bot
+type Param struct {
+	Name  string
+	Type  ParamType
+	Descr string
+	// The value of the parameter if it is not given, or nil if
+	// the parameter is required
+	Default interface{}
+}
+
+// Params maps the names of parameters to their values
+//
+// The values have the types designated by the ParamType of the
+// parameter, see Kind.Parse.
+type Params map[string]interface{}
+
+// Uint returns the value of the integer parameter NAME
+func (p Params) Uint(name string) uint {
+	v, _ := p[name].(uint)
+	return v
+}
+
+// Text returns the value of the string parameter NAME
+func (p Params) Text(name string) string {
+	v, _ := p[name].(string)
+	return v
+}
+
+// Floats returns the value of the parameter NAME that is a list of
+// numbers
+func (p Params) Floats(name string) []float64 {
+	v, _ := p[name].([]float64)
+	return v
+}
+
+// Kind describes a kind of bot that can be configured
+type Kind struct {
+	Name   string
+	Descr  string
+	Params []Param
+	// Title returns the default name of a bot with the
+	// parameters PARAMS
+	Title func(params Params) string
+	// Make creates a bot represented by USER, that plays MANCALA
+	// and has the parameters PARAMS.  Parameters that cannot be
+	// used together are reported as errors.
+	Make func(mancala kgp.Mancala, user *kgp.User, params Params) (kgp.Agent, error)
+}
+
+// Convert the configuration value V into a value of type T
+func convert(t ParamType, v interface{}) (interface{}, bool) {
+	switch t {
+	case PARAM_UINT:
+		switch n := v.(type) {
+		case uint:
+			return n, true
+		case int64:
+			return uint(n), n >= 0
+		case int:
+			return uint(n), n >= 0
+		}
+	case PARAM_STRING:
+		s, ok := v.(string)
+		return s, ok
+	case PARAM_FLOATS:
+		var list []interface{}
+		switch l := v.(type) {
+		case []float64:
+			return l, true
+		case []interface{}:
+			list = l
+		default:
+			return nil, false
+		}
+		fs := make([]float64, 0, len(list))
+		for _, f := range list {
+			switch f := f.(type) {
+			case float64:
+				fs = append(fs, f)
+			case int64:
+				fs = append(fs, float64(f))
+			default:
+				return nil, false
+			}
+		}
+		return fs, true
+	}
+	return nil, false
+}
+
+// Parse checks the values of RAW against the parameters of a kind
+//
+// The values are converted into the types designated by their
+// parameters and missing values are replaced by their default.
+func (k *Kind) Parse(raw map[string]interface{}) (Params, error) {
+	params := make(Params)
+	for name, v := range raw {
+		var param *Param
+		for i := range k.Params {
+			if k.Params[i].Name == name {
+				param = &k.Params[i]
+			}
+		}
+		if param == nil {
+			return nil, fmt.Errorf("Unknown parameter %q for %s", name, k.Name)
+		}
+		val, ok := convert(param.Type, v)
+		if !ok {
+			return nil, fmt.Errorf("The parameter %q must be %s", name, param.Type)
+		}
+		params[name] = val
+	}
+	for _, param := range k.Params {
+		if _, ok := params[param.Name]; ok {
+			continue
+		}
+		if param.Default == nil {
+			return nil, fmt.Errorf("Missing parameter %q for %s", param.Name, k.Name)
+		}
+		params[param.Name] = param.Default
+	}
+	return params, nil
+}
+
+var (
+	kinds     = make(map[string]*Kind)
+	kindsLock sync.Mutex
+)
+
+// Register a kind of bot
+//
+// Registering two kinds with the same name is an error.
+func Register(k *Kind) {
+	kindsLock.Lock()
+	defer kindsLock.Unlock()
+
+	if _, ok := kinds[k.Name]; ok {
+		panic(fmt.Sprintf("The kind of bot %q was registered twice", k.Name))
+	}
+	kinds[k.Name] = k
+}
+
+// Lookup returns the kind of bot with the name NAME
+func Lookup(name string) (*Kind, error) {
+	kindsLock.Lock()
+	defer kindsLock.Unlock()
+
+	k, ok := kinds[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown kind of bot %q", name)
+	}
+	return k, nil
+}
+
+// Kinds returns all registered kinds of bots, ordered by their names
+func Kinds() []*Kind {
+	kindsLock.Lock()
+	defer kindsLock.Unlock()
+
+	var list []*Kind
+	for _, k := range kinds {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Token returns the token of the bot named NAME
+//
+// As the token only depends on the name, the database entry of a bot
+// is preserved when the server is restarted.  Clients may not use
+// tokens reserved for bots, see kgp.BOT_TOKEN_PREFIX.
+func Token(name string) string {
+	return kgp.BOT_TOKEN_PREFIX + name
+}
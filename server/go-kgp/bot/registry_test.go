@@ -0,0 +1,98 @@
+// Bot Registry Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParams(t *testing.T) {
+	kind, err := Lookup("minmax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range []struct {
+		raw    map[string]interface{}
+		params Params
+		title  string
+	}{
+		{
+			raw:    map[string]interface{}{"depth": int64(4)},
+			params: Params{"depth": uint(4), "eval": "", "weights": []float64{}},
+			title:  "MinMax-4",
+		},
+		{
+			raw: map[string]interface{}{
+				"depth":   int64(2),
+				"eval":    "weighted",
+				"weights": []interface{}{int64(1), 0.5, int64(0), int64(0), int64(2)},
+			},
+			params: Params{
+				"depth":   uint(2),
+				"eval":    "weighted",
+				"weights": []float64{1, 0.5, 0, 0, 2},
+			},
+			title: "MinMax-2 (weighted(1,0.5,0,0,2))",
+		},
+		{raw: map[string]interface{}{}},
+		{raw: map[string]interface{}{"depth": int64(-1)}},
+		{raw: map[string]interface{}{"depth": "4"}},
+		{raw: map[string]interface{}{"depth": int64(4), "eval": 1.0}},
+		{raw: map[string]interface{}{"depth": int64(4), "weights": []interface{}{"1"}}},
+		{raw: map[string]interface{}{"depth": int64(4), "width": int64(4)}},
+	} {
+		params, err := kind.Parse(test.raw)
+		if test.params == nil {
+			if err == nil {
+				t.Errorf("(%d) Expected %v to fail", i, test.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("(%d) Unexpected error: %s", i, err)
+		} else if !reflect.DeepEqual(params, test.params) {
+			t.Errorf("(%d) Expected %v, got %v", i, test.params, params)
+		} else if title := kind.Title(params); title != test.title {
+			t.Errorf("(%d) Expected the title %q, got %q", i, test.title, title)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if _, err := Lookup("unknown"); err == nil {
+		t.Error("Expected an unknown kind to fail")
+	}
+
+	var names []string
+	for _, k := range Kinds() {
+		names = append(names, k.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"minmax"}) {
+		t.Errorf("Unexpected kinds %v", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a kind twice to fail")
+		}
+	}()
+	Register(&Kind{Name: "minmax"})
+}
@@ -0,0 +1,180 @@
+// Client SDK for writing KGP agents
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+// Package client implements the client side of the Kalah Game
+// Protocol, so that agents can be written in Go directly.
+//
+// An agent only has to implement the Agent interface and is then
+// passed to Run, that takes care of connecting to a server,
+// responding to ping requests, identifying the agent and forwarding
+// state requests to the agent:
+//
+//	err := client.Run(ctx, &client.Config{
+//		Server: "kgps://kalah.kwarc.info",
+//		Token:  "my secret token",
+//		Name:   "My Agent",
+//	}, myAgent)
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"time"
+
+	"go-kgp"
+)
+
+// A decision made by an agent
+type Move struct {
+	Pit     uint   // Pit to sow, starting with 0
+	Comment string // Optional comment for the move
+}
+
+// An Agent decides what move to make
+type Agent interface {
+	// Search is invoked for every state request.  The agent
+	// plays as SIDE on BOARD, and should send improving moves on
+	// the returned channel, until the search is over, after which
+	// the channel must be closed.  Closing the channel yields the
+	// current state.  When the server is no longer interested in
+	// any moves, CTX is cancelled and all further moves are
+	// ignored.  Multiple searches may run concurrently.
+	Search(ctx context.Context, board *kgp.Board, side kgp.Side) <-chan Move
+}
+
+// Configuration of a client
+type Config struct {
+	// Address of the server.  Addresses starting with "ws://"
+	// or "wss://" are connected to via Websocket, "kgps://" via
+	// TLS, and all other addresses via plain TCP.
+	Server string
+
+	// Identity of the agent, sent to the server using "set".  An
+	// agent should always be connected using the same token.
+	Token       string
+	Name        string
+	Authors     string
+	Description string
+
+	// Game the agent wants to play.  Kalah is played using the
+	// "freeplay" mode and Oware using the "oware" mode.
+	Mancala kgp.Mancala
+
+	// Board the agent wants to play on, if the server offers
+	// several configurations.  A Size or Init of zero leaves the
+	// choice to the server.
+	Size, Init uint
+
+	// If Reconnect is set, the client reconnects whenever the
+	// connection was lost or the server said goodbye, waiting
+	// for an exponentially increasing time between MinBackoff
+	// and MaxBackoff.
+	Reconnect  bool
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Logger for errors and other messages (optional)
+	Log *log.Logger
+
+	// Dial is used to connect to a server (optional)
+	Dial func(ctx context.Context, addr string) (io.ReadWriteCloser, error)
+}
+
+// Default backoff when reconnecting
+const (
+	DEFAULT_MIN_BACKOFF = time.Second
+	DEFAULT_MAX_BACKOFF = 2 * time.Minute
+)
+
+// Error returned by Run if the server has ended the session
+var ErrGoodbye = errors.New("Server said goodbye")
+
+// Backoff computes the delay before the N'th attempt to reconnect
+//
+// The delay grows exponentially from MIN to MAX, with a random jitter
+// of up to 50%.  If MIN or MAX are not positive, the default backoff
+// is used instead.
+func Backoff(n uint, min, max time.Duration) time.Duration {
+	if min <= 0 {
+		min = DEFAULT_MIN_BACKOFF
+	}
+	if max <= 0 {
+		max = DEFAULT_MAX_BACKOFF
+	}
+
+	d := max
+	if n < 32 && min<<n < max && min<<n > 0 {
+		d = min << n
+	}
+
+	// Add jitter of up to 50%, to avoid all clients reconnecting
+	// at the same time after a server restart
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Run connects AGENT to the server described by CONF
+//
+// Run returns when the context is cancelled or the connection to the
+// server has ended and the client should not reconnect.
+func Run(ctx context.Context, conf *Config, agent Agent) error {
+	if conf.Log == nil {
+		conf.Log = log.New(io.Discard, "", 0)
+	}
+	dial := conf.Dial
+	if dial == nil {
+		dial = Dial
+	}
+
+	var attempt uint
+	for {
+		rwc, err := dial(ctx, conf.Server)
+		if err == nil {
+			s := &session{
+				conf:     conf,
+				agent:    agent,
+				rwc:      rwc,
+				searches: make(map[uint64]context.CancelFunc),
+			}
+			err = s.run(ctx)
+			if s.greeted {
+				attempt = 0
+			}
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case !conf.Reconnect:
+			return err
+		}
+
+		wait := Backoff(attempt, conf.MinBackoff, conf.MaxBackoff)
+		conf.Log.Printf("Connection ended (%s), reconnecting in %s", err, wait)
+		attempt++
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Client SDK Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"go-kgp"
+	"go-kgp/msg"
+)
+
+// An agent that proposes every legal move and then waits to be stopped
+// or closes the channel if FINISH is set
+type agent struct {
+	finish  bool
+	stopped chan struct{}
+}
+
+func (a *agent) Search(ctx context.Context, board *kgp.Board, side kgp.Side) <-chan Move {
+	c := make(chan Move)
+	go func() {
+		defer close(c)
+		size, _ := board.Type()
+		for i := uint(0); i < size; i++ {
+			if !board.Legal(side, i) {
+				continue
+			}
+			select {
+			case c <- Move{Pit: i, Comment: fmt.Sprint("pit ", i)}:
+			case <-ctx.Done():
+				a.stopped <- struct{}{}
+				return
+			}
+		}
+		if !a.finish {
+			<-ctx.Done()
+			a.stopped <- struct{}{}
+		}
+	}()
+	return c
+}
+
+// A fake server on the other end of a pipe
+type server struct {
+	t    *testing.T
+	conn net.Conn
+	scan *bufio.Scanner
+}
+
+func (s *server) send(format string, args ...interface{}) {
+	_, err := fmt.Fprintf(s.conn, format+"\r\n", args...)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func (s *server) expect(name string, args ...string) *msg.Command {
+	s.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if !s.scan.Scan() {
+		s.t.Fatalf("Expected %q, connection ended: %v", name, s.scan.Err())
+	}
+	cmd, err := msg.Lex(s.scan.Text())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if cmd.Name != name || len(cmd.Args) != len(args) {
+		s.t.Fatalf("Expected %q %q, got %q", name, args, cmd)
+	}
+	for i, arg := range args {
+		if cmd.Args[i].Text != arg {
+			s.t.Fatalf("Expected %q %q, got %q", name, args, cmd)
+		}
+	}
+	return cmd
+}
+
+// Start a client and return the server side of the first connection
+func start(t *testing.T, conf *Config, a Agent) (*server, <-chan error, context.CancelFunc) {
+	conns := make(chan net.Conn, 1)
+	conf.Dial = func(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+		c, s := net.Pipe()
+		conns <- s
+		return c, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- Run(ctx, conf, a) }()
+
+	conn := <-conns
+	scan := bufio.NewScanner(conn)
+	scan.Split(msg.SplitCommands())
+	return &server{t: t, conn: conn, scan: scan}, errc, cancel
+}
+
+func TestSession(t *testing.T) {
+	a := &agent{stopped: make(chan struct{}, 4)}
+	s, errc, cancel := start(t, &Config{
+		Token: "token",
+		Name:  "name",
+	}, a)
+	defer cancel()
+
+	s.send("kgp 1 0 0")
+	s.expect("set", "auth:token", "token")
+	s.expect("set", "info:name", "name")
+	s.expect("mode", "freeplay")
+
+	s.send("3 ping")
+	if cmd := s.expect("pong"); cmd.Ref != 3 {
+		t.Errorf("Expected pong to reference 3, got %d", cmd.Ref)
+	}
+
+	// The agent streams all legal moves, counting from 1
+	s.send("5 state <3,0,0,0,2,1,0,3,3>")
+	s.expect("set", "info:comment", "pit 1")
+	if cmd := s.expect("move", "2"); cmd.Ref != 5 {
+		t.Errorf("Expected move to reference 5, got %d", cmd.Ref)
+	}
+	s.expect("set", "info:comment", "pit 2")
+	s.expect("move", "3")
+
+	// Stopping the request cancels the search
+	s.send("@5 stop")
+	select {
+	case <-a.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Search was not stopped")
+	}
+
+	s.send("goodbye")
+	s.expect("goodbye")
+	if err := <-errc; err != ErrGoodbye {
+		t.Fatalf("Expected %q, got %v", ErrGoodbye, err)
+	}
+}
+
+func TestYield(t *testing.T) {
+	a := &agent{finish: true, stopped: make(chan struct{}, 4)}
+	s, _, cancel := start(t, &Config{}, a)
+	defer cancel()
+
+	s.send("kgp 1 0 0")
+	s.expect("mode", "freeplay")
+
+	// Concurrent requests are handled independently
+	s.send("7 state <1,0,0,1,1>")
+	s.send("9 state <2,0,0,1,1,1,1>")
+	refs := make(map[uint64]int)
+	for i := 0; i < 5; i++ {
+		s.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if !s.scan.Scan() {
+			t.Fatal(s.scan.Err())
+		}
+		cmd, err := msg.Lex(s.scan.Text())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cmd.Name == "move" || cmd.Name == "yield" {
+			refs[cmd.Ref]++
+		}
+		if cmd.Name == "set" {
+			i--
+		}
+	}
+	if refs[7] != 2 || refs[9] != 3 {
+		t.Fatalf("Unexpected responses: %v", refs)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	conns := make(chan net.Conn)
+	conf := &Config{
+		Reconnect:  true,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		Dial: func(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+			c, s := net.Pipe()
+			select {
+			case conns <- s:
+				return c, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- Run(ctx, conf, &agent{}) }()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case conn := <-conns:
+			conn.Close()
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Client did not reconnect (%d)", i)
+		}
+	}
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("Expected %q, got %v", context.Canceled, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for n, max := range []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		16 * time.Second, 32 * time.Second, time.Minute, time.Minute,
+	} {
+		for i := 0; i < 100; i++ {
+			d := Backoff(uint(n), time.Second, time.Minute)
+			if d < max/2 || d > max {
+				t.Fatalf("Backoff %d is %s, expected %s to %s",
+					n, d, max/2, max)
+			}
+		}
+	}
+	if d := Backoff(100, time.Second, time.Minute); d > time.Minute {
+		t.Fatalf("Backoff exceeded maximum: %s", d)
+	}
+}
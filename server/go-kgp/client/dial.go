@@ -0,0 +1,121 @@
+// Connecting to a server
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Default ports for plain and TLS connections
+const (
+	DEFAULT_PORT     = "2671"
+	DEFAULT_TLS_PORT = "2672"
+)
+
+// adapted from https://github.com/gorilla/websocket/issues/282
+
+// wsrwc is a read-write-closer using websockets
+type wsrwc struct {
+	*websocket.Conn
+	r io.Reader
+}
+
+// WebSocket wraps CONN into a read-write-closer
+//
+// Every write is sent as a separate text message, and messages are
+// read as a continuous stream.
+func WebSocket(conn *websocket.Conn) io.ReadWriteCloser {
+	return &wsrwc{Conn: conn}
+}
+
+// Convert a write call to a Websocket message
+func (c *wsrwc) Write(p []byte) (int, error) {
+	err := c.WriteMessage(websocket.TextMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Convert a read call into a Websocket query
+func (c *wsrwc) Read(p []byte) (int, error) {
+	for {
+		if c.r == nil {
+			// Advance to next message.
+			var err error
+			_, c.r, err = c.NextReader()
+			if err != nil {
+				return 0, err
+			}
+		}
+		n, err := c.r.Read(p)
+		if err == io.EOF {
+			// At end of message.
+			c.r = nil
+			if n == 0 {
+				// No data read, continue to next message.
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Dial connects to the server at ADDR
+//
+// Addresses starting with "ws://" or "wss://" are connected to via
+// Websocket, "kgps://" via TLS and "kgp://" or addresses without a
+// scheme via plain TCP.  If no port is given, the default port is
+// used.
+func Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	if strings.HasPrefix(addr, "ws://") || strings.HasPrefix(addr, "wss://") {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		return WebSocket(conn), nil
+	}
+
+	port := DEFAULT_PORT
+	secure := strings.HasPrefix(addr, "kgps://")
+	if secure {
+		port = DEFAULT_TLS_PORT
+		addr = strings.TrimPrefix(addr, "kgps://")
+	} else {
+		addr = strings.TrimPrefix(addr, "kgp://")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	if secure {
+		d := tls.Dialer{}
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
@@ -0,0 +1,255 @@
+// Handling of a single connection
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go-kgp"
+	"go-kgp/msg"
+)
+
+// A session manages a single connection to a server
+type session struct {
+	conf    *Config
+	agent   Agent
+	rwc     io.ReadWriteCloser
+	rid     uint64     // last ID that was used
+	wlock   sync.Mutex // write lock
+	greeted bool       // did the server greet the client
+	rules   kgp.Rules  // rules announced for the current game
+
+	// Running searches, indexed by the ID of the state request
+	searches map[uint64]context.CancelFunc
+	slock    sync.Mutex
+}
+
+// Send a command to the server, optionally referencing TO
+func (s *session) respond(to uint64, name string, args ...interface{}) uint64 {
+	id := atomic.AddUint64(&s.rid, 2)
+	line := msg.Make(id, to, name, args...).String()
+
+	defer s.wlock.Unlock()
+	s.wlock.Lock()
+
+	_, err := fmt.Fprint(s.rwc, line, "\r\n")
+	if err != nil {
+		s.conf.Log.Print(err)
+		return 0
+	}
+	return id
+}
+
+// Identify the client and request the freeplay mode
+func (s *session) greet(cmd *msg.Command) error {
+	v, err := msg.ParseVersion(cmd)
+	if err != nil {
+		s.respond(cmd.Id, "error", err.Error())
+		return err
+	}
+	if v != msg.Current {
+		s.conf.Log.Printf("Server uses protocol version %s", v)
+	}
+
+	for _, opt := range []struct{ key, val string }{
+		{"auth:token", s.conf.Token},
+		{"info:name", s.conf.Name},
+		{"info:authors", s.conf.Authors},
+		{"info:description", s.conf.Description},
+	} {
+		if opt.val != "" {
+			s.respond(0, "set", msg.Word(opt.key), opt.val)
+		}
+	}
+	for _, opt := range []struct {
+		key string
+		val uint
+	}{
+		{"board:size", s.conf.Size},
+		{"board:init", s.conf.Init},
+	} {
+		if opt.val != 0 {
+			s.respond(0, "set", msg.Word(opt.key), uint64(opt.val))
+		}
+	}
+	switch s.conf.Mancala {
+	case kgp.KALAH:
+		s.respond(0, "mode", "freeplay")
+	case kgp.OWARE:
+		s.respond(0, "mode", "oware")
+	}
+	s.greeted = true
+	return nil
+}
+
+// Forward a state request to the agent and the moves to the server
+func (s *session) search(ctx context.Context, id uint64, board *kgp.Board) {
+	defer func() {
+		s.slock.Lock()
+		if cancel, ok := s.searches[id]; ok {
+			cancel()
+			delete(s.searches, id)
+		}
+		s.slock.Unlock()
+	}()
+
+	size, _ := board.Type()
+	moves := s.agent.Search(ctx, board, kgp.South)
+	for {
+		select {
+		case m, ok := <-moves:
+			if !ok {
+				if ctx.Err() == nil {
+					s.respond(id, "yield")
+				}
+				return
+			}
+			if m.Pit >= size || !board.Legal(kgp.South, m.Pit) {
+				s.conf.Log.Printf("Agent proposed illegal move %d for %s",
+					m.Pit, board)
+				continue
+			}
+			if m.Comment != "" {
+				s.respond(0, "set", msg.Word("info:comment"), m.Comment)
+			}
+			// Pits are counted starting with 1 in KGP
+			s.respond(id, "move", uint64(m.Pit+1))
+		case <-ctx.Done():
+			// Discard all remaining moves, so that the
+			// agent is not blocked.
+			go func() {
+				for range moves {
+				}
+			}()
+			return
+		}
+	}
+}
+
+// Handle a single command sent by the server
+func (s *session) interpret(ctx context.Context, input string) error {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+
+	cmd, err := msg.Lex(input)
+	if err != nil {
+		var id uint64
+		if cmd != nil {
+			id = cmd.Id
+		}
+		s.respond(id, "error", err.Error())
+		return nil
+	}
+
+	switch cmd.Name {
+	case "kgp":
+		return s.greet(cmd)
+	case "state":
+		var board *kgp.Board
+		err = cmd.Scan(&board)
+		if err != nil {
+			s.respond(cmd.Id, "error", err.Error())
+			return nil
+		}
+		board.SetRules(s.rules)
+		board.SetMancala(s.conf.Mancala)
+
+		sctx, cancel := context.WithCancel(ctx)
+		s.slock.Lock()
+		if old, ok := s.searches[cmd.Id]; ok {
+			old()
+		}
+		s.searches[cmd.Id] = cancel
+		s.slock.Unlock()
+		go s.search(sctx, cmd.Id, board)
+	case "stop":
+		s.slock.Lock()
+		if cancel, ok := s.searches[cmd.Ref]; ok {
+			cancel()
+			delete(s.searches, cmd.Ref)
+		}
+		s.slock.Unlock()
+	case "set":
+		var key, val string
+		if cmd.Scan(&key, &val) != nil {
+			return nil
+		}
+		switch key {
+		case "game:id":
+			// A new game uses the default rules, unless
+			// announced otherwise
+			s.rules = kgp.DefaultRules
+		case "game:rules":
+			rules, err := kgp.ParseRules(val)
+			if err != nil {
+				s.conf.Log.Printf("Unknown rules %q: %s", val, err)
+			}
+			s.rules = rules
+		}
+	case "ping":
+		s.respond(cmd.Id, "pong")
+	case "error":
+		var reason string
+		if cmd.Scan(&reason) == nil {
+			s.conf.Log.Printf("Server reported an error: %s", reason)
+		}
+	case "goodbye":
+		return ErrGoodbye
+	}
+
+	return nil
+}
+
+// Process commands until the connection is closed
+func (s *session) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Close the connection when the context is cancelled, so that
+	// the scanner is interrupted
+	go func() {
+		<-ctx.Done()
+		s.rwc.Close()
+	}()
+
+	var err error
+	scanner := bufio.NewScanner(s.rwc)
+	scanner.Split(msg.SplitCommands())
+	for err == nil && scanner.Scan() {
+		err = s.interpret(ctx, scanner.Text())
+	}
+	if err == ErrGoodbye {
+		s.respond(0, "goodbye")
+	} else if err == nil {
+		err = scanner.Err()
+		if err == nil {
+			err = io.EOF
+		}
+	}
+
+	return err
+}
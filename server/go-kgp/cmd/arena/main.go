@@ -0,0 +1,211 @@
+// Offline arena
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"go-kgp"
+	"go-kgp/bot"
+	"go-kgp/conf"
+	"go-kgp/game"
+	"go-kgp/local"
+)
+
+// A participant in a match
+type contestant struct {
+	name     string
+	make     func() kgp.Agent // create an agent for a new game
+	timeouts uint             // moves that were not decided in time
+}
+
+// Parse an agent specification
+//
+// MinMax bots are denoted as understood by bot.ParseMinMax.
+// Everything else is a command that is invoked for every move.
+func parse(ctx context.Context, c *conf.Conf, spec string) (*contestant, error) {
+	depth, eval, err := bot.ParseMinMax(spec)
+	if err == nil {
+		return &contestant{
+			name: spec,
+			make: func() kgp.Agent {
+				return bot.MakeMinMaxEval(kgp.KALAH, depth, eval)
+			},
+		}, nil
+	} else if err != bot.ErrNotMinMax {
+		return nil, err
+	}
+
+	args := strings.Fields(spec)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Invalid agent %q", spec)
+	}
+	agent, err := local.Join(ctx, c, spec, local.Process(args))
+	if err != nil {
+		return nil, err
+	}
+	return &contestant{
+		name: spec,
+		make: func() kgp.Agent { return agent },
+	}, nil
+}
+
+func main() {
+	var (
+		size    = flag.Uint("size", 8, "Number of pits on every side")
+		stones  = flag.Uint("stones", 8, "Number of stones in every pit")
+		games   = flag.Uint("games", 10, "Number of games to play")
+		timeout = flag.Duration("time", conf.Default(false).MoveTimeout,
+			"Time an external agent has for every move")
+		debug = flag.Bool("debug", false, "Enable debugging output")
+		seed  = flag.Int64("seed", 0,
+			"Seed for random fallback moves (0 to use the current time)")
+		name = flag.String("game", "kalah",
+			"Game to play (kalah, or oware which ignores -size and -stones)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [options] [agent] [agent]\n\n"+
+				"An agent is either \"mmN\" for the MinMax bot with a search\n"+
+				"depth of N, or a command using the kgpc protocol.  The bot\n"+
+				"can use a different evaluation, e.g. \"mm6:mobility\" or\n"+
+				"\"mm6:weighted:1,0.5,0,1,0.5\" (stores, stones, mobility,\n"+
+				"extra, captures).\n\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *size == 0 {
+		log.Fatal("The board must have at least one pit")
+	}
+	mancala, err := kgp.ParseMancala(*name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var (
+		lock     sync.Mutex
+		fallback = make(map[kgp.Agent]uint)
+	)
+	c := local.Configure(*timeout, *debug, func(m *kgp.Move) {
+		if m.Source == kgp.FALLBACK {
+			lock.Lock()
+			fallback[m.Agent]++
+			lock.Unlock()
+		}
+	})
+
+	c.Seed = *seed
+	c.Random()
+	fmt.Printf("Using the random seed %d\n\n", c.Seed)
+
+	var players [2]*contestant
+	for i := range players {
+		p, err := parse(ctx, c, flag.Arg(i))
+		if err != nil {
+			log.Fatal(err)
+		}
+		players[i] = p
+	}
+
+	var s stats
+	for i := uint(0); i < *games && ctx.Err() == nil; i++ {
+		// The first agent plays south in every other game
+		a, b := players[0].make(), players[1].make()
+		board := kgp.MakeBoard(*size, *stones)
+		if mancala == kgp.OWARE {
+			board = kgp.MakeOware()
+		}
+		g := &kgp.Game{
+			Board: board,
+			Id:    uint64(i + 1),
+			South: a,
+			North: b,
+			Seed:  c.Random().Int63(),
+		}
+		if i%2 == 1 {
+			g.South, g.North = b, a
+		}
+		game.Play(ctx, g, c)
+
+		var result string
+		switch g.State {
+		case kgp.NORTH_WON, kgp.SOUTH_RESIGNED:
+			if g.North == a {
+				s.wins++
+			} else {
+				s.losses++
+			}
+			result = "North won"
+		case kgp.SOUTH_WON, kgp.NORTH_RESIGNED:
+			if g.South == a {
+				s.wins++
+			} else {
+				s.losses++
+			}
+			result = "South won"
+		case kgp.UNDECIDED:
+			s.draws++
+			result = "Draw"
+		default:
+			result = "Aborted"
+		}
+		if g.State == kgp.NORTH_RESIGNED || g.State == kgp.SOUTH_RESIGNED {
+			result += " by resignation"
+		}
+
+		south, north := players[0], players[1]
+		if g.South != a {
+			south, north = north, south
+		}
+		fmt.Printf("Game %d: %s (South) vs. %s (North): %s, %d:%d\n",
+			g.Id, south.name, north.name, result,
+			g.Board.Store(kgp.South), g.Board.Store(kgp.North))
+
+		lock.Lock()
+		players[0].timeouts += fallback[a]
+		players[1].timeouts += fallback[b]
+		delete(fallback, a)
+		delete(fallback, b)
+		lock.Unlock()
+	}
+
+	fmt.Printf("\n%s vs. %s: %s\n", players[0].name, players[1].name, &s)
+	for _, p := range players {
+		if p.timeouts > 0 {
+			fmt.Printf("%s did not decide on %d moves in time\n",
+				p.name, p.timeouts)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Match statistics
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// z-score for a 95% confidence interval
+const Z95 = 1.959964
+
+// Results from the perspective of the first agent
+type stats struct {
+	wins, draws, losses uint
+}
+
+func (s *stats) games() uint {
+	return s.wins + s.draws + s.losses
+}
+
+// Score rate, where a draw counts as half a win
+func (s *stats) score() float64 {
+	n := float64(s.games())
+	if n == 0 {
+		return math.NaN()
+	}
+	return (float64(s.wins) + float64(s.draws)/2) / n
+}
+
+// Confidence interval of the score rate
+//
+// The interval is the Wilson score interval, which unlike the normal
+// approximation does not collapse when all games had the same result.
+// Draws are not taken into account, so that the interval is
+// conservative if games were drawn.
+func (s *stats) interval() (float64, float64) {
+	var (
+		n  = float64(s.games())
+		p  = s.score()
+		zz = Z95 * Z95 / n
+		c  = (p + zz/2) / (1 + zz)
+		d  = Z95 / (1 + zz) * math.Sqrt(p*(1-p)/n+zz/(4*n))
+	)
+	return math.Max(0, c-d), math.Min(1, c+d)
+}
+
+// Convert a score rate into an Elo difference
+//
+// A score rate of 0 or 1 would correspond to an infinite difference,
+// so the rate is kept half a game away from these extremes.
+func (s *stats) elo(p float64) float64 {
+	ε := 1 / (2 * float64(s.games()))
+	p = math.Max(ε, math.Min(1-ε, p))
+	return 400 * math.Log10(p/(1-p))
+}
+
+func (s *stats) String() string {
+	if s.games() == 0 {
+		return "no games"
+	}
+	p := s.score()
+	lo, hi := s.interval()
+	return fmt.Sprintf("%d wins, %d draws, %d losses, score %.1f%% [%.1f%%, %.1f%%], Elo %+.0f [%+.0f, %+.0f]",
+		s.wins, s.draws, s.losses,
+		100*p, 100*lo, 100*hi,
+		s.elo(p), s.elo(lo), s.elo(hi))
+}
@@ -0,0 +1,63 @@
+// Match statistics tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+
+	for i, test := range []struct {
+		s      stats
+		score  float64
+		lo, hi float64
+		elo    float64
+	}{
+		{stats{5, 0, 5}, 0.5, 0.237, 0.763, 0},
+		{stats{0, 10, 0}, 0.5, 0.237, 0.763, 0},
+		{stats{75, 0, 25}, 0.75, 0.657, 0.825, 190.85},
+		{stats{60, 30, 10}, 0.75, 0.657, 0.825, 190.85},
+		{stats{10, 0, 0}, 1, 0.722, 1, 511.50},
+		{stats{0, 0, 10}, 0, 0, 0.278, -511.50},
+	} {
+		if p := test.s.score(); !near(p, test.score) {
+			t.Errorf("(%d) Expected score %f, got %f", i, test.score, p)
+		}
+		lo, hi := test.s.interval()
+		if !near(lo, test.lo) || !near(hi, test.hi) {
+			t.Errorf("(%d) Expected interval [%f, %f], got [%f, %f]",
+				i, test.lo, test.hi, lo, hi)
+		}
+		if lo == hi {
+			t.Errorf("(%d) The interval is empty", i)
+		}
+		for _, b := range []float64{lo, hi} {
+			if e := test.s.elo(b); math.IsInf(e, 0) || math.IsNaN(e) {
+				t.Errorf("(%d) Elo of the bound %f is %f", i, b, e)
+			}
+		}
+		if e := test.s.elo(test.s.score()); !near(e, test.elo) {
+			t.Errorf("(%d) Expected Elo %f, got %f", i, test.elo, e)
+		}
+	}
+}
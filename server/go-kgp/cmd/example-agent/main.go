@@ -0,0 +1,101 @@
+// Example agent using the client package
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"go-kgp"
+	"go-kgp/bot"
+	"go-kgp/client"
+)
+
+// An agent using iterative deepening
+type deepening struct {
+	max uint // maximal search depth
+}
+
+func (d deepening) Search(ctx context.Context, board *kgp.Board, side kgp.Side) <-chan client.Move {
+	moves := make(chan client.Move)
+	go func() {
+		defer close(moves)
+		last := ^uint(0)
+		for depth := uint(1); depth <= d.max; depth++ {
+			move, eval := bot.Search(board, side, depth)
+			if move == last {
+				// Avoid sending redundant commands, as
+				// servers may limit the command rate.
+				continue
+			}
+			last = move
+			select {
+			case moves <- client.Move{
+				Pit:     move,
+				Comment: fmt.Sprintf("Depth %d, evaluation %d", depth, eval),
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return moves
+}
+
+func main() {
+	var (
+		server = flag.String("server", "kgp://localhost", "Address of the server")
+		token  = flag.String("token", "", "Token to authenticate the agent")
+		name   = flag.String("name", "Example Agent", "Name of the agent")
+		depth  = flag.Uint("depth", 12, "Maximal search depth")
+		retry  = flag.Bool("reconnect", false, "Reconnect when disconnected")
+		game   = flag.String("game", "kalah", "Game to play (kalah or oware)")
+		size   = flag.Uint("size", 0, "Number of pits to request (0 for any)")
+		stones = flag.Uint("stones", 0, "Number of stones to request (0 for any)")
+	)
+	flag.Parse()
+	mancala, err := kgp.ParseMancala(*game)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = client.Run(ctx, &client.Config{
+		Server:      *server,
+		Token:       *token,
+		Name:        *name,
+		Authors:     "go-kgp",
+		Description: "Iterative deepening using MinMax with Alpha-Beta pruning",
+		Mancala:     mancala,
+		Size:        *size,
+		Init:        *stones,
+		Reconnect:   *retry,
+		Log:         log.Default(),
+	}, deepening{max: *depth})
+	if err != nil && err != context.Canceled && err != client.ErrGoodbye {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,141 @@
+// Move tree counting
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go-kgp"
+)
+
+// Print the count C that took D to compute, prefixed by NAME
+func report(name string, c kgp.Perft, d time.Duration) {
+	nps := float64(c.Nodes) / d.Seconds()
+	fmt.Printf("%-6s %14d %14d %12d %12d %10s %14.0f\n", name,
+		c.Nodes, c.Leaves, c.Terminal, c.Repeats,
+		d.Round(time.Millisecond), nps)
+}
+
+func main() {
+	var (
+		size   = flag.Uint("size", 8, "Number of pits on every side, unless a board is given")
+		stones = flag.Uint("stones", 8, "Number of stones in every pit, unless a board is given")
+		depth  = flag.Uint("depth", 6, "Number of moves to count")
+		north  = flag.Bool("north", false, "Let north make the first move")
+		divide = flag.Bool("divide", false, "Count the moves of the first side separately")
+		rules  = flag.String("rules", kgp.DefaultRules.String(), "Rules to play by")
+		name   = flag.String("game", "kalah",
+			"Game to play (kalah, or oware which ignores -size and -stones)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [options] [board]\n\n"+
+				"Count all positions that can be reached from a board, given\n"+
+				"in the KGP representation (e.g. \"<3,0,0,3,3,3,3,3,3>\").\n\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	mancala, err := kgp.ParseMancala(*name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r, err := kgp.ParseRules(*rules)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var board *kgp.Board
+	switch {
+	case flag.NArg() == 1:
+		board, err = kgp.Parse(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		board.SetMancala(mancala)
+	case mancala == kgp.OWARE:
+		board = kgp.MakeOware()
+	case *size == 0:
+		log.Fatal("The board must have at least one pit")
+	default:
+		board = kgp.MakeBoard(*size, *stones)
+	}
+	board.SetRules(r)
+	if err := board.Validate(board.Type()); err != nil {
+		log.Fatalf("Invalid board %s: %s", board, err)
+	}
+	p, err := kgp.Pack(board)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	side := kgp.South
+	if *north {
+		side = kgp.North
+	}
+	fmt.Printf("Counting %d moves by %s on %s (%s, %s)\n\n",
+		*depth, side, board, mancala, r)
+	fmt.Printf("%-6s %14s %14s %12s %12s %10s %14s\n",
+		"", "nodes", "leaves", "terminal", "repeats", "time", "nodes/s")
+
+	if *divide {
+		var (
+			total kgp.Perft
+			start = time.Now()
+		)
+		size, _ := board.Type()
+		for m := uint(0); m < size; m++ {
+			if *depth == 0 || !p.Legal(side, m) {
+				continue
+			}
+
+			var (
+				c     = kgp.Perft{Nodes: 1}
+				n     = p
+				next  = !side
+				begin = time.Now()
+			)
+			if n.Sow(side, m) {
+				c.Repeats++
+				next = side
+			}
+			c.Add(n.Perft(next, *depth-1))
+			report(fmt.Sprintf("%d", m+1), c, time.Since(begin))
+			total.Add(c)
+		}
+		report("total", total, time.Since(start))
+		return
+	}
+
+	for d := uint(1); d <= *depth; d++ {
+		start := time.Now()
+		c := p.Perft(side, d)
+		report(fmt.Sprintf("%d", d), c, time.Since(start))
+	}
+}
@@ -0,0 +1,244 @@
+// Reproduction of recorded games
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"go-kgp"
+	"go-kgp/bot"
+	"go-kgp/conf"
+	"go-kgp/db"
+	"go-kgp/game"
+)
+
+// Parse a list of game IDs and ranges of IDs (e.g. "3-7")
+func parseIds(args []string) ([]int, error) {
+	var ids []int
+	for _, arg := range args {
+		from, to := arg, arg
+		if i := strings.IndexByte(arg, '-'); i > 0 {
+			from, to = arg[:i], arg[i+1:]
+		}
+		a, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid game %q", arg)
+		}
+		b, err := strconv.Atoi(to)
+		if err != nil || b < a {
+			return nil, fmt.Errorf("Invalid range %q", arg)
+		}
+		for id := a; id <= b; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Pairings reproduces the seeds drawn by the scheduler
+type pairings struct {
+	rng   *rand.Rand
+	seeds []int64
+	limit int
+}
+
+// Find the number of the pairing that drew SEED
+//
+// If the seed was not drawn within the limit, false is returned.
+func (p *pairings) find(seed int64) (int, bool) {
+	for i, s := range p.seeds {
+		if s == seed {
+			return i + 1, true
+		}
+	}
+	for len(p.seeds) < p.limit {
+		p.seeds = append(p.seeds, p.rng.Int63())
+		if p.seeds[len(p.seeds)-1] == seed {
+			return len(p.seeds), true
+		}
+	}
+	return 0, false
+}
+
+// Create the initial board of a game
+func initial(b *kgp.Board) *kgp.Board {
+	if b.Mancala() == kgp.OWARE {
+		return kgp.MakeOware()
+	}
+
+	init := kgp.MakeBoard(b.Type())
+	init.SetRules(b.Rules())
+	return init
+}
+
+// Replay the game G consisting of the moves MOVES
+//
+// All problems that were found are returned.
+func replay(g *kgp.Game, moves []*kgp.Move, c *conf.Conf) (problems []string) {
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if o := g.Opening; o != nil {
+		start := initial(g.Board)
+		p := bot.Opening(start,
+			c.OpeningPlies,
+			c.OpeningDepth,
+			c.OpeningMargin,
+			rand.New(rand.NewSource(g.Seed)))
+		if p.Board.String() != o.Board.String() || p.Current != o.Current {
+			report("expected the opening %s, but generated %s",
+				o.Board, p.Board)
+		}
+	}
+
+	size, init := g.Board.Type()
+	if err := g.Board.Validate(size, init); err != nil {
+		report("the initial board %s is invalid: %s", g.Board, err)
+	}
+
+	r := &kgp.Game{
+		Board:   g.Board.Copy(),
+		South:   g.South,
+		North:   g.North,
+		Current: g.Current,
+		Seed:    g.Seed,
+	}
+	for i, m := range moves {
+		if r.Board.Over() {
+			report("move %d was made after the game ended", i+1)
+			return
+		}
+		if m.Source == kgp.FALLBACK {
+			fallback := r.Board.Random(r.Current, r.Rand())
+			if fallback != m.Choice {
+				report("expected the random move %d for move %d, but got %d",
+					m.Choice+1, i+1, fallback+1)
+			}
+		}
+		if !game.Move(r, &kgp.Move{Choice: m.Choice, Agent: r.Active()}) {
+			report("move %d (%d) is illegal on %s", i+1, m.Choice+1, r.Board)
+			return
+		}
+		if err := r.Board.Validate(size, init); err != nil {
+			report("invalid board after move %d: %s", i+1, err)
+		}
+		if m.State != nil && m.State.String() != r.Board.String() {
+			report("expected %s after move %d, but got %s",
+				m.State, i+1, r.Board)
+		}
+	}
+	return
+}
+
+func main() {
+	var (
+		file   = flag.String("db", "data.db", "File to use for the database")
+		seed   = flag.Int64("seed", 0, "Seed of the scheduler (0 to skip checking pairings)")
+		limit  = flag.Int("pairings", 1000000, "Number of pairings to search for a seed")
+		config = conf.Default(false)
+	)
+	flag.UintVar(&config.OpeningPlies, "opening-plies", config.OpeningPlies,
+		"Number of random moves that openings were generated with")
+	flag.UintVar(&config.OpeningDepth, "opening-depth", config.OpeningDepth,
+		"Search depth that openings were evaluated with")
+	flag.UintVar(&config.OpeningMargin, "opening-margin", config.OpeningMargin,
+		"Maximal evaluation of a balanced opening")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [options] [game or range of games ...]\n\n"+
+				"Replay the recorded moves of games and check that all random\n"+
+				"decisions are reproduced from the seeds of the games.\n\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ids, err := parseIds(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(ids) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	config.Database = *file
+	db.Prepare(config)
+
+	p := pairings{
+		rng:   rand.New(rand.NewSource(*seed)),
+		limit: *limit,
+	}
+	failed := false
+	for _, id := range ids {
+		var (
+			gc    = make(chan *kgp.Game, 1)
+			mc    = make(chan *kgp.Move)
+			moves []*kgp.Move
+		)
+		go config.DB.QueryGame(context.Background(), id, gc, mc)
+		g := <-gc
+		for m := range mc {
+			moves = append(moves, m)
+		}
+		if g == nil {
+			fmt.Printf("Game %d: not found\n", id)
+			failed = true
+			continue
+		}
+		if g.Expired {
+			fmt.Printf("Game %d: the moves have expired\n", id)
+			continue
+		}
+
+		var pairing string
+		if *seed != 0 {
+			if n, ok := p.find(g.Seed); ok {
+				pairing = fmt.Sprintf(" (pairing %d)", n)
+			} else {
+				pairing = " (not scheduled with this seed)"
+				failed = true
+			}
+		}
+
+		problems := replay(g, moves, config)
+		if len(problems) == 0 {
+			fmt.Printf("Game %d%s: reproduced %d moves\n", id, pairing, len(moves))
+			continue
+		}
+		failed = true
+		fmt.Printf("Game %d%s: could not be reproduced\n", id, pairing)
+		for _, p := range problems {
+			fmt.Printf("\t%s\n", p)
+		}
+	}
+
+	config.DB.Shutdown()
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -42,6 +42,7 @@ func main() {
 		debug    = flag.Bool("debug", false, "Enable debugging mode")
 	)
 
+	conf.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 	if flag.NArg() != 0 {
 		fmt.Fprintf(flag.CommandLine.Output(),
@@ -55,7 +56,7 @@ func main() {
 	config, err := conf.Open(*confFile, *debug)
 	if err != nil && (!os.IsNotExist(err) || *confFile != defconf) {
 		log.Fatal(err)
-	} else {
+	} else if err != nil {
 		config = conf.Default(*debug)
 	}
 	config.Debug.Println("Debug logging has been enabled")
@@ -69,18 +70,26 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Managers are shut down in the reverse order of registration,
+	// so that no new connections are accepted while the running
+	// games are finishing, after which all clients are
+	// disconnected and finally the database is closed.
+
 	// Enable the database
 	db.Prepare(config)
 
+	// Disconnect clients on shutdown
+	proto.PrepareClients(config)
+
+	// Use the random scheduler
+	config.Register(conf.GameManager(sched.MakeRandom(config)))
+
 	// Enable the web interface
 	web.Prepare(config)
 
 	// Allow TCP connections
 	proto.Prepare(config)
 
-	// Use the random scheduler
-	config.Register(conf.GameManager(sched.MakeRandom(config)))
-
 	// Launch the server
 	config.Start()
 }
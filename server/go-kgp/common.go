@@ -22,6 +22,7 @@ package kgp
 import (
 	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 )
 
@@ -29,6 +30,7 @@ type (
 	Side    bool
 	Outcome uint8
 	State   uint8
+	Source  uint8
 )
 
 const (
@@ -51,6 +53,16 @@ const (
 	ABORTED
 )
 
+// Possible origins of a move
+const (
+	CHOSEN    Source = iota // Decided by the agent
+	AUTOMATIC               // The only legal move
+	FALLBACK                // Random move, the agent did not decide
+)
+
+// Tokens with this prefix are reserved for bots
+const BOT_TOKEN_PREFIX = "bot:"
+
 func (o Outcome) String() string {
 	switch o {
 	case WIN:
@@ -122,6 +134,38 @@ func (s *State) Scan(src interface{}) error {
 	return nil
 }
 
+func (s Source) String() string {
+	switch s {
+	case CHOSEN:
+		return "c"
+	case AUTOMATIC:
+		return "a"
+	case FALLBACK:
+		return "f"
+	default:
+		panic(fmt.Sprintf("Illegal source: %d", s))
+	}
+}
+
+func (s *Source) Scan(src interface{}) error {
+	str, ok := src.(string)
+	if !ok {
+		return errors.New(`invalid type`)
+	}
+
+	switch str {
+	case "c":
+		*s = CHOSEN
+	case "a":
+		*s = AUTOMATIC
+	case "f":
+		*s = FALLBACK
+	default:
+		return errors.New(`unknown source`)
+	}
+	return nil
+}
+
 type Agent interface {
 	Request(*Game) (*Move, bool)
 	User() *User
@@ -137,6 +181,41 @@ type User struct {
 	Games  uint64
 }
 
+// Standing summarises the results of an agent in a game
+type Standing struct {
+	User  *User
+	Won   uint64
+	Drawn uint64
+	Lost  uint64
+
+	// The Elo rating of the agent, or zero if the agent has not
+	// been rated yet
+	Rating float64
+}
+
+// Games returns the number of finished games
+func (s *Standing) Games() uint64 {
+	return s.Won + s.Drawn + s.Lost
+}
+
+// Score returns the share of points, where a draw counts half a win
+func (s *Standing) Score() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	return (float64(s.Won) + float64(s.Drawn)/2) / float64(s.Games())
+}
+
+// Opening is a position a game may start from instead of the initial
+// board
+type Opening struct {
+	Id      int64
+	Board   *Board
+	Current Side  // side to move
+	Plies   uint  // number of random moves that were made
+	Eval    int64 // evaluation of the position for the side to move
+}
+
 type Game struct {
 	// The board the game is being played on
 	Board     *Board
@@ -146,6 +225,25 @@ type Game struct {
 	Current   Side
 	State     State
 	MoveCount uint
+	// Have the moves of this game been deleted?
+	Expired bool
+	// The position the game started from, if it did not start
+	// from the initial board
+	Opening *Opening
+	// The seed for all random decisions made during the game
+	Seed int64
+	rng  *rand.Rand
+}
+
+// Rand returns the source of randomness for the game
+//
+// The source is created from the seed of the game when first
+// requested, so that random decisions can be reproduced.
+func (g *Game) Rand() *rand.Rand {
+	if g.rng == nil {
+		g.rng = rand.New(rand.NewSource(g.Seed))
+	}
+	return g.rng
 }
 
 func (g *Game) Side(a Agent) Side {
@@ -178,7 +276,12 @@ type Move struct {
 	Choice  uint
 	Comment string
 	Agent   Agent
-	State   *Board
-	Game    *Game
-	Stamp   time.Time
+	// The board after the move was made
+	State *Board
+	Game  *Game
+	Stamp time.Time
+	// Time the agent took to decide
+	Think time.Duration
+	// How the move was decided
+	Source Source
 }
@@ -0,0 +1,137 @@
+// Board Configurations
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package conf
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// A board configuration that new Kalah games may be played on
+type Board struct {
+	Size   uint `toml:"size"`   // pits per side
+	Init   uint `toml:"init"`   // stones per pit
+	Weight uint `toml:"weight"` // relative frequency
+}
+
+func (b Board) String() string {
+	return fmt.Sprintf("%dx%d", b.Size, b.Init)
+}
+
+// ParseBoards parses a comma separated list of board configurations
+//
+// Each configuration has the form "SIZExINIT", optionally followed by
+// a colon and a weight (e.g. "6x4:2,8x8").  The default weight is 1.
+func ParseBoards(spec string) ([]Board, error) {
+	var boards []Board
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		b := Board{Weight: 1}
+		if i := strings.IndexByte(part, ':'); i >= 0 {
+			w, err := strconv.ParseUint(part[i+1:], 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid weight in %q", part)
+			}
+			b.Weight = uint(w)
+			part = part[:i]
+		}
+
+		dims := strings.SplitN(part, "x", 2)
+		if len(dims) != 2 {
+			return nil, fmt.Errorf("Invalid board %q", part)
+		}
+		size, err := strconv.ParseUint(dims[0], 10, 0)
+		if err != nil || size == 0 {
+			return nil, fmt.Errorf("Invalid size in %q", part)
+		}
+		init, err := strconv.ParseUint(dims[1], 10, 0)
+		if err != nil || init == 0 {
+			return nil, fmt.Errorf("Invalid number of stones in %q", part)
+		}
+		b.Size, b.Init = uint(size), uint(init)
+		boards = append(boards, b)
+	}
+	return boards, nil
+}
+
+// Boards returns the configurations new Kalah games may be played on
+//
+// If no set of boards has been configured, all games are played on
+// BoardSize pits with BoardInit stones.
+func (c *Conf) Boards() []Board {
+	if len(c.BoardSet) == 0 {
+		return []Board{{Size: c.BoardSize, Init: c.BoardInit, Weight: 1}}
+	}
+	return c.BoardSet
+}
+
+// Return all configurations with SIZE pits and INIT stones
+//
+// A SIZE or INIT of zero matches any configuration.
+func (c *Conf) candidates(size, init uint) (candidates []Board, total uint) {
+	for _, b := range c.Boards() {
+		if (size != 0 && b.Size != size) || (init != 0 && b.Init != init) {
+			continue
+		}
+		candidates = append(candidates, b)
+		total += b.Weight
+	}
+	return
+}
+
+// Supports checks if a game can be played with SIZE pits and INIT
+// stones, where zero indicates no restriction
+func (c *Conf) Supports(size, init uint) bool {
+	candidates, _ := c.candidates(size, init)
+	return len(candidates) > 0
+}
+
+// Choose a board configuration for a new game
+//
+// Configurations are drawn from RNG according to their weight.  A
+// non-zero SIZE or INIT restricts the choice to configurations with
+// the same number of pits or stones.  If no configuration satisfies
+// the restrictions, false is returned.
+func (c *Conf) Choose(size, init uint, rng *rand.Rand) (Board, bool) {
+	candidates, total := c.candidates(size, init)
+
+	if len(candidates) == 0 {
+		return Board{}, false
+	}
+	if total == 0 {
+		// Configurations without a weight are only played
+		// when explicitly requested
+		return candidates[rng.Intn(len(candidates))], true
+	}
+	n := uint(rng.Int63n(int64(total)))
+	for _, b := range candidates {
+		if n < b.Weight {
+			return b, true
+		}
+		n -= b.Weight
+	}
+	panic("Unreachable")
+}
@@ -0,0 +1,107 @@
+// Board Configuration Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package conf
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseBoards(t *testing.T) {
+	for i, test := range []struct {
+		spec   string
+		boards []Board
+		fail   bool
+	}{
+		{spec: "", boards: nil},
+		{spec: "6x4", boards: []Board{{6, 4, 1}}},
+		{spec: "6x4:3, 8x8", boards: []Board{{6, 4, 3}, {8, 8, 1}}},
+		{spec: "8x8:0", boards: []Board{{8, 8, 0}}},
+		{spec: "6", fail: true},
+		{spec: "0x4", fail: true},
+		{spec: "6x0", fail: true},
+		{spec: "6x4:-1", fail: true},
+		{spec: "axb", fail: true},
+	} {
+		boards, err := ParseBoards(test.spec)
+		if test.fail {
+			if err == nil {
+				t.Errorf("(%d) Expected %q to fail", i, test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("(%d) Unexpected error: %s", i, err)
+		} else if !reflect.DeepEqual(boards, test.boards) {
+			t.Errorf("(%d) Expected %v, got %v", i, test.boards, boards)
+		}
+	}
+}
+
+func TestChoose(t *testing.T) {
+	c := Default(false)
+	c.BoardSet = []Board{{6, 4, 1}, {8, 8, 3}, {8, 4, 0}}
+	rng := rand.New(rand.NewSource(2671))
+
+	count := make(map[Board]int)
+	for i := 0; i < 4000; i++ {
+		b, ok := c.Choose(0, 0, rng)
+		if !ok {
+			t.Fatal("No board was chosen")
+		}
+		count[b]++
+	}
+	if count[c.BoardSet[2]] != 0 {
+		t.Error("A board without a weight was chosen")
+	}
+	if n := count[c.BoardSet[1]]; n < 2700 || n > 3300 {
+		t.Errorf("Unexpected distribution: %v", count)
+	}
+
+	for i, test := range []struct {
+		size, init uint
+		board      Board
+		ok         bool
+	}{
+		{size: 6, board: Board{6, 4, 1}, ok: true},
+		{init: 8, board: Board{8, 8, 3}, ok: true},
+		{size: 8, init: 8, board: Board{8, 8, 3}, ok: true},
+		{size: 8, init: 4, board: Board{8, 4, 0}, ok: true},
+		{size: 6, init: 8, ok: false},
+		{size: 7, ok: false},
+	} {
+		b, ok := c.Choose(test.size, test.init, rng)
+		if ok != c.Supports(test.size, test.init) {
+			t.Errorf("(%d) Choose and Supports disagree", i)
+		}
+		if ok != test.ok {
+			t.Errorf("(%d) Expected ok to be %t", i, test.ok)
+		} else if ok && b != test.board {
+			t.Errorf("(%d) Expected %s, got %s", i, test.board, b)
+		}
+	}
+
+	// Without a set of boards, the default board is used
+	c.BoardSet = nil
+	if b, ok := c.Choose(0, 0, rng); !ok || b.Size != c.BoardSize || b.Init != c.BoardInit {
+		t.Errorf("Expected the default board, got %s", b)
+	}
+}
@@ -0,0 +1,143 @@
+// Bot Configuration
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package conf
+
+import (
+	"fmt"
+
+	"go-kgp"
+	"go-kgp/bot"
+)
+
+// A bot that is scheduled for games
+//
+// The parameters depend on the kind of bot, see bot.Kind.
+type Bot struct {
+	Name   string     // name of the bot, shared by all instances
+	Type   string     // kind of bot, see bot.Lookup
+	Count  uint       // number of instances
+	Params bot.Params // parameters of the kind
+}
+
+func (b Bot) String() string {
+	return b.Name
+}
+
+// Keys of a bot description that are not parameters of the kind
+var botKeys = []string{"name", "type", "count"}
+
+// Parse a bot description
+//
+// A bot is either described by a table or by an integer, that
+// designates the depth of a MinMax bot.  All keys of a table, except
+// for those in botKeys, are parameters of the kind of bot.  Unless it
+// is given, the name of a bot is derived from its parameters.
+func parseBot(data interface{}) (Bot, error) {
+	var (
+		b   = Bot{Type: "minmax", Count: 1}
+		raw = make(map[string]interface{})
+	)
+
+	switch v := data.(type) {
+	case int64:
+		raw["depth"] = v
+	case map[string]interface{}:
+		for key, val := range v {
+			var ok bool
+			switch key {
+			case "name":
+				b.Name, ok = val.(string)
+				ok = ok && b.Name != ""
+			case "type":
+				b.Type, ok = val.(string)
+			case "count":
+				var n int64
+				n, ok = val.(int64)
+				ok = ok && n >= 0
+				b.Count = uint(n)
+			default:
+				raw[key] = val
+				ok = true
+			}
+			if !ok {
+				return b, fmt.Errorf("invalid value for the bot parameter %q", key)
+			}
+		}
+	default:
+		return b, fmt.Errorf("invalid bot description %v", data)
+	}
+
+	kind, err := bot.Lookup(b.Type)
+	if err != nil {
+		return b, err
+	}
+	b.Params, err = kind.Parse(raw)
+	if err != nil {
+		return b, err
+	}
+	if b.Name == "" {
+		b.Name = kind.Title(b.Params)
+	}
+	return b, nil
+}
+
+// Return COUNT instances of MinMax bots for each of DEPTHS
+func minmaxBots(count uint, depths ...uint) []Bot {
+	var bots []Bot
+	for _, depth := range depths {
+		b, err := parseBot(map[string]interface{}{
+			"depth": int64(depth),
+			"count": int64(count),
+		})
+		if err != nil {
+			panic(err)
+		}
+		bots = append(bots, b)
+	}
+	return bots
+}
+
+// Return a table that describes the bot, see parseBot
+func (b Bot) table() map[string]interface{} {
+	t := map[string]interface{}{
+		"name":  b.Name,
+		"type":  b.Type,
+		"count": b.Count,
+	}
+	for key, val := range b.Params {
+		t[key] = val
+	}
+	return t
+}
+
+// Make creates an instance of the bot that plays MANCALA
+//
+// All instances of a bot share the same token, that only depends on
+// the name of the bot.
+func (b Bot) Make(mancala kgp.Mancala) (kgp.Agent, error) {
+	kind, err := bot.Lookup(b.Type)
+	if err != nil {
+		return nil, err
+	}
+	return kind.Make(mancala, &kgp.User{
+		Token: bot.Token(b.Name),
+		Name:  b.Name,
+	}, b.Params)
+}
@@ -0,0 +1,141 @@
+// Bot Configuration Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package conf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-kgp"
+	"go-kgp/bot"
+)
+
+// Return the description of a MinMax bot
+func minmax(name string, depth uint, eval string, weights []float64, count uint) Bot {
+	if weights == nil {
+		weights = []float64{}
+	}
+	return Bot{
+		Name:  name,
+		Type:  "minmax",
+		Count: count,
+		Params: bot.Params{
+			"depth":   depth,
+			"eval":    eval,
+			"weights": weights,
+		},
+	}
+}
+
+func TestLoadBots(t *testing.T) {
+	for i, test := range []struct {
+		spec string
+		bots []Bot
+		fail bool
+	}{
+		{spec: ``, bots: defaultConfig.Bots},
+		{spec: `bots = []`, bots: []Bot{}},
+		{
+			spec: `bots = [2, 4]`,
+			bots: []Bot{
+				minmax("MinMax-2", 2, "", nil, 1),
+				minmax("MinMax-4", 4, "", nil, 1),
+			},
+		},
+		{
+			spec: `bots = [
+				{type = "minmax", depth = 6, eval = "mobility", count = 2},
+				{name = "Balanced", depth = 4, eval = "weighted", weights = [1, 0.5, 0, 2, 1]},
+			]`,
+			bots: []Bot{
+				minmax("MinMax-6 (mobility)", 6, "mobility", nil, 2),
+				minmax("Balanced", 4, "weighted", []float64{1, 0.5, 0, 2, 1}, 1),
+			},
+		},
+		{spec: `bots = [2, 2]`, fail: true},
+		{spec: `bots = [{name = "A", depth = 2}, {name = "A", depth = 4}]`, fail: true},
+		{spec: `bots = [{name = "", depth = 2}]`, fail: true},
+		{spec: `bots = [{type = "random"}]`, fail: true},
+		{spec: `bots = [{type = "minmax"}]`, fail: true},
+		{spec: `bots = [{depth = 4, eval = "unknown"}]`, fail: true},
+		{spec: `bots = [{depth = 4, eval = "weighted", weights = [1]}]`, fail: true},
+		{spec: `bots = [{depth = 4, eval = "stores", weights = [1]}]`, fail: true},
+		{spec: `bots = [{depth = 4, eval = 4}]`, fail: true},
+		{spec: `bots = [{depth = -4}]`, fail: true},
+		{spec: `bots = [{depht = 4}]`, fail: true},
+		{spec: `bots = ["mm4"]`, fail: true},
+	} {
+		c, err := load(strings.NewReader("[game.open]\n"+test.spec), false)
+		if test.fail {
+			if err == nil {
+				t.Errorf("(%d) Expected %q to fail", i, test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("(%d) Unexpected error: %s", i, err)
+		} else if !reflect.DeepEqual(c.Bots, test.bots) {
+			t.Errorf("(%d) Expected %v, got %v", i, test.bots, c.Bots)
+		}
+	}
+}
+
+func TestDumpBots(t *testing.T) {
+	c, err := load(strings.NewReader(`
+[game.open]
+bots = [
+	{name = "Balanced", depth = 4, eval = "weighted", weights = [1, 0.5, 0, 2, 1], count = 3},
+	6,
+]
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := load(&buf, false)
+	if err != nil {
+		t.Fatalf("Failed to load the dumped configuration: %s", err)
+	}
+	if !reflect.DeepEqual(c.Bots, d.Bots) {
+		t.Errorf("Expected %v, got %v", c.Bots, d.Bots)
+	}
+}
+
+func TestBotToken(t *testing.T) {
+	b, err := parseBot(map[string]interface{}{"name": "Deep", "depth": int64(8)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []kgp.Mancala{kgp.KALAH, kgp.OWARE} {
+		a, err := b.Make(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := a.User(); u.Token != "bot:Deep" || u.Name != "Deep" {
+			t.Errorf("Unexpected user %q with the token %q", u.Name, u.Token)
+		}
+	}
+}
@@ -21,8 +21,10 @@ package conf
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"time"
 
 	"go-kgp"
@@ -32,22 +34,50 @@ import (
 type conf struct {
 	Debug    bool `toml:"debug"`
 	Database struct {
-		File string `toml:"file"`
+		File      string `toml:"file"`
+		Retention string `toml:"retention"`
+		Days      uint   `toml:"retention-days"`
 	} `toml:"database"`
 	Proto struct {
 		Port      uint `toml:"port"`
 		Ping      bool `toml:"ping"`
 		Timeout   uint `toml:"timeout"`
+		Reconnect uint `toml:"reconnect"`
 		Websocket bool `toml:"websocket"`
+		TLS       struct {
+			Port uint   `toml:"port"`
+			Cert string `toml:"cert"`
+			Key  string `toml:"key"`
+		} `toml:"tls"`
+		Limit struct {
+			IP    uint   `toml:"ip"`
+			Token uint   `toml:"token"`
+			Rate  uint   `toml:"rate"`
+			Burst uint   `toml:"burst"`
+			Bans  string `toml:"bans"`
+		} `toml:"limit"`
 	} `toml:"proto"`
 	Game struct {
-		Timeout uint   `toml:"timeout"`
-		Mode    string `toml:"mode"`
-		Open    struct {
-			Init uint   `toml:"init"`
-			Size uint   `toml:"init"`
-			Bots []uint `toml:"bots"`
+		Timeout  uint   `toml:"timeout"`
+		Resume   uint   `toml:"resume"`
+		Drain    uint   `toml:"shutdown"`
+		Mode     string `toml:"mode"`
+		Seed     int64  `toml:"seed"`
+		Rules    string `toml:"rules"`
+		EarlyWin *bool  `toml:"earlywin"` // deprecated, see Rules
+		Open     struct {
+			Init   uint          `toml:"init"`
+			Size   uint          `toml:"size"`
+			Boards []Board       `toml:"boards"`
+			Sizes  []uint        `toml:"sizes"`  // deprecated, see Boards
+			Stones []uint        `toml:"stones"` // deprecated, see Boards
+			Bots   []interface{} `toml:"bots"`   // see parseBot
 		} `toml:"open"`
+		Opening struct {
+			Plies  uint `toml:"plies"`
+			Depth  uint `toml:"depth"`
+			Margin uint `toml:"margin"`
+		} `toml:"opening"`
 	} `toml:"game"`
 	Web struct {
 		Enabled bool   `toml:"enabled"`
@@ -57,6 +87,27 @@ type conf struct {
 	} `toml:"web"`
 }
 
+// Data retention policies
+const (
+	// Moves are never deleted
+	RETAIN_FOREVER = "forever"
+	// Moves are deleted after a number of days
+	RETAIN_DAYS = "days"
+	// Moves are deleted after a number of days, unless the game
+	// was played in a tournament or has been archived
+	RETAIN_PRACTICE = "practice"
+)
+
+// Scheduling modes
+const (
+	// Agents are paired randomly and play a single game from the
+	// initial board
+	MODE_RANDOM = "random"
+	// Agents are paired randomly and play two games from the same
+	// balanced opening, swapping sides after the first game
+	MODE_DUPLICATE = "duplicate"
+)
+
 // Public configuration
 type Conf struct {
 	Log   *log.Logger
@@ -67,15 +118,43 @@ type Conf struct {
 	Ping       bool          // Should KGP send ping requests
 	TCPTimeout time.Duration // Disconnect after this timeout
 	WebSocket  bool          // Are Websocket connection enabled
+	TLSPort    uint          // Port for accepting TLS connections
+	TLSCert    string        // Certificate file (enables TLS)
+	TLSKey     string        // Private key file for TLSCert
+
+	// Time a client has to reconnect and continue a game
+	ReconnectTimeout time.Duration
+
+	// Connection limits (0 disables a limit)
+	MaxConnIP    uint   // Concurrent connections per IP address
+	MaxConnToken uint   // Concurrent connections per token
+	CommandRate  uint   // Commands per second per connection
+	CommandBurst uint   // Commands that may exceed CommandRate
+	BanFile      string // File listing banned tokens and networks
 
 	// Database Configuration
-	Database string // File to store the database
-	DB       DatabaseManager
+	Database      string // File to store the database
+	Retention     string // Policy for expiring moves
+	RetentionDays uint   // Days to keep moves for
+	DB            DatabaseManager
 
 	// Game Configuration
-	MoveTimeout time.Duration
-	Play        chan *kgp.Game
-	GM          GameManager
+	MoveTimeout   time.Duration
+	ResumeTimeout time.Duration // Grace period to resume interrupted games
+	DrainTimeout  time.Duration // Grace period for games when shutting down
+	Play          chan *kgp.Game
+	GM            GameManager
+	Mode          string // Scheduling mode (see MODE_*)
+	Validate      bool   // Validate the board after every move
+
+	// Randomness
+	Seed int64      // Seed for scheduling decisions (0 to use the clock)
+	Rand *rand.Rand // Source for scheduling decisions, see Random
+
+	// Opening generation for the duplicate mode
+	OpeningPlies  uint // Random moves made from the initial board
+	OpeningDepth  uint // Search depth to evaluate an opening
+	OpeningMargin uint // Maximal evaluation of a balanced opening
 
 	// Website configuration
 	WebInterface bool   // Has the web interface been enabled?
@@ -84,9 +163,11 @@ type Conf struct {
 	WebPort      uint   // Port that the web server listens on
 
 	// Public Tournament configuration
-	BoardInit uint
-	BoardSize uint
-	BotTypes  map[uint]uint
+	BoardInit  uint
+	BoardSize  uint
+	BoardSet   []Board // Weighted configurations, see Boards
+	BoardRules kgp.Rules
+	Bots       []Bot // Bots that are scheduled for games
 
 	// Internal state
 	man []Manager // List of system managers
@@ -103,17 +184,37 @@ var defaultConfig = Conf{
 	Ping:       true,
 	TCPTimeout: time.Second * 20,
 	WebSocket:  true,
+	TLSPort:    2672,
+
+	ReconnectTimeout: time.Second * 30,
+
+	// Websocket clients all connect through the web server, and
+	// machines behind NAT share an address, so that connections
+	// are not limited by IP address unless requested
+	MaxConnIP:    0,
+	MaxConnToken: 4,
+	CommandRate:  20,
+	CommandBurst: 50,
 
 	// Database configuration
-	Database: "data.db",
+	Database:      "data.db",
+	Retention:     RETAIN_DAYS,
+	RetentionDays: 7,
 
 	// Game Configuration
-	MoveTimeout: time.Second * 5,
+	MoveTimeout:   time.Second * 5,
+	ResumeTimeout: time.Minute,
+	DrainTimeout:  time.Second * 30,
+	Mode:          MODE_RANDOM,
+
+	OpeningPlies:  4,
+	OpeningDepth:  6,
+	OpeningMargin: 2,
 
 	// Public Tournament configuration
 	BoardInit: 8,
 	BoardSize: 8,
-	BotTypes:  map[uint]uint{2: 4, 4: 4, 6: 4, 8: 4},
+	Bots:      minmaxBots(4, 2, 4, 6, 8),
 
 	// Website configuration
 	WebInterface: true,
@@ -121,21 +222,94 @@ var defaultConfig = Conf{
 	About:        "",
 }
 
-func init() {
-	flag.StringVar(&defaultConfig.About, "about", defaultConfig.About,
+// Register command line flags to modify the default configuration
+//
+// The flags are only registered when requested, so that programs
+// using the server packages (see cmd/arena) are not burdened with
+// flags they have no use for.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&defaultConfig.About, "about", defaultConfig.About,
 		"File to use for the about template")
-	flag.UintVar(&defaultConfig.WebPort, "wwwport", defaultConfig.WebPort,
+	fs.UintVar(&defaultConfig.WebPort, "wwwport", defaultConfig.WebPort,
 		"Port to use for the HTTP server")
-	flag.UintVar(&defaultConfig.BoardInit, "board-init", defaultConfig.BoardInit,
+	fs.UintVar(&defaultConfig.BoardInit, "board-init", defaultConfig.BoardInit,
 		"Default number of stones to use for Kalah boards")
-	flag.UintVar(&defaultConfig.BoardSize, "board-size", defaultConfig.BoardSize,
+	fs.UintVar(&defaultConfig.BoardSize, "board-size", defaultConfig.BoardSize,
 		"Default size to use for Kalah boards")
-	flag.StringVar(&defaultConfig.Database, "db", defaultConfig.Database,
+	fs.Func("boards", "Weighted board configurations to choose from (e.g. \"6x4:2,8x8\")",
+		func(spec string) (err error) {
+			defaultConfig.BoardSet, err = ParseBoards(spec)
+			return
+		})
+	fs.Func("board-rules", "Rule variant to use for Kalah boards (e.g. \"capture=always earlywin=no\")",
+		func(spec string) (err error) {
+			defaultConfig.BoardRules, err = kgp.ParseRules(spec)
+			return
+		})
+	fs.Func("mode", "Scheduling mode (random or duplicate)",
+		func(mode string) error {
+			switch mode {
+			case MODE_RANDOM, MODE_DUPLICATE:
+				defaultConfig.Mode = mode
+				return nil
+			}
+			return fmt.Errorf("Unknown scheduling mode %q", mode)
+		})
+	fs.Int64Var(&defaultConfig.Seed, "seed", defaultConfig.Seed,
+		"Seed for all scheduling decisions (0 to use the current time)")
+	fs.UintVar(&defaultConfig.OpeningPlies, "opening-plies", defaultConfig.OpeningPlies,
+		"Number of random moves to generate an opening in the duplicate mode")
+	fs.UintVar(&defaultConfig.OpeningDepth, "opening-depth", defaultConfig.OpeningDepth,
+		"Search depth to evaluate openings in the duplicate mode")
+	fs.UintVar(&defaultConfig.OpeningMargin, "opening-margin", defaultConfig.OpeningMargin,
+		"Maximal evaluation of a balanced opening in the duplicate mode")
+	fs.DurationVar(&defaultConfig.ResumeTimeout, "resume", defaultConfig.ResumeTimeout,
+		"Time to wait for agents to resume interrupted games (0 to disable)")
+	fs.DurationVar(&defaultConfig.DrainTimeout, "shutdown", defaultConfig.DrainTimeout,
+		"Time to wait for running games to finish when shutting down")
+	fs.StringVar(&defaultConfig.Database, "db", defaultConfig.Database,
 		"File to use for the database")
-	flag.BoolVar(&defaultConfig.Ping, "ping", defaultConfig.Ping,
+	fs.StringVar(&defaultConfig.Retention, "retention", defaultConfig.Retention,
+		"Policy for expiring moves (forever, days or practice)")
+	fs.UintVar(&defaultConfig.RetentionDays, "retention-days", defaultConfig.RetentionDays,
+		"Number of days to retain moves for")
+	fs.BoolVar(&defaultConfig.Ping, "ping", defaultConfig.Ping,
 		"Enable ping as a keepalive check")
-	flag.UintVar(&defaultConfig.TCPPort, "tcpport", defaultConfig.TCPPort,
+	fs.DurationVar(&defaultConfig.ReconnectTimeout, "reconnect", defaultConfig.ReconnectTimeout,
+		"Time to wait for a disconnected agent to reconnect (0 to disable)")
+	fs.UintVar(&defaultConfig.TCPPort, "tcpport", defaultConfig.TCPPort,
 		"Port to use for TCP connections")
-	flag.StringVar(&defaultConfig.Data, "data", defaultConfig.Data,
+	fs.UintVar(&defaultConfig.TLSPort, "tlsport", defaultConfig.TLSPort,
+		"Port to use for TLS connections")
+	fs.StringVar(&defaultConfig.TLSCert, "tlscert", defaultConfig.TLSCert,
+		"Certificate file to use for TLS connections")
+	fs.StringVar(&defaultConfig.TLSKey, "tlskey", defaultConfig.TLSKey,
+		"Private key file to use for TLS connections")
+	fs.UintVar(&defaultConfig.MaxConnIP, "max-conn-ip", defaultConfig.MaxConnIP,
+		"Maximal number of concurrent connections per IP address (0 to disable)")
+	fs.UintVar(&defaultConfig.MaxConnToken, "max-conn-token", defaultConfig.MaxConnToken,
+		"Maximal number of concurrent connections per token (0 to disable)")
+	fs.UintVar(&defaultConfig.CommandRate, "rate", defaultConfig.CommandRate,
+		"Maximal number of commands per second per connection (0 to disable)")
+	fs.UintVar(&defaultConfig.CommandBurst, "burst", defaultConfig.CommandBurst,
+		"Number of commands a connection may send in excess of the rate")
+	fs.StringVar(&defaultConfig.BanFile, "bans", defaultConfig.BanFile,
+		"File listing banned tokens and networks (reloaded on SIGHUP)")
+	fs.StringVar(&defaultConfig.Data, "data", defaultConfig.Data,
 		"Directory to use for hosting /data/ requests")
 }
+
+// Random returns the source of randomness for scheduling decisions
+//
+// Unless a source has been set, it is created from Seed, or from the
+// current time if no seed has been configured, in which case the seed
+// is stored in Seed.  The source is not safe for concurrent use.
+func (c *Conf) Random() *rand.Rand {
+	if c.Rand == nil {
+		if c.Seed == 0 {
+			c.Seed = time.Now().UnixNano()
+		}
+		c.Rand = rand.New(rand.NewSource(c.Seed))
+	}
+	return c.Rand
+}
@@ -20,6 +20,7 @@
 package conf
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -38,13 +39,14 @@ func (c *Conf) debug(enabled bool) {
 	} else {
 		c.Debug = log.New(io.Discard, "", 0)
 	}
+	c.Validate = enabled
 }
 
 // Parse a configuration from R into CONF
 func load(r io.Reader, debug bool) (*Conf, error) {
 	// Load configuration data
 	var data conf
-	_, err := toml.NewDecoder(r).Decode(&data)
+	md, err := toml.NewDecoder(r).Decode(&data)
 	if err != nil {
 		return nil, err
 	}
@@ -57,19 +59,130 @@ func load(r io.Reader, debug bool) (*Conf, error) {
 	c.TCPPort = data.Proto.Port
 	c.TCPTimeout = time.Duration(data.Proto.Timeout) * time.Millisecond
 	c.Ping = data.Proto.Ping
+	if md.IsDefined("proto", "reconnect") {
+		c.ReconnectTimeout = time.Duration(data.Proto.Reconnect) * time.Millisecond
+	}
 	c.WebSocket = data.Proto.Websocket
+	if md.IsDefined("proto", "limit", "ip") {
+		c.MaxConnIP = data.Proto.Limit.IP
+	}
+	if md.IsDefined("proto", "limit", "token") {
+		c.MaxConnToken = data.Proto.Limit.Token
+	}
+	if md.IsDefined("proto", "limit", "rate") {
+		c.CommandRate = data.Proto.Limit.Rate
+	}
+	if md.IsDefined("proto", "limit", "burst") {
+		c.CommandBurst = data.Proto.Limit.Burst
+	}
+	if md.IsDefined("proto", "limit", "bans") {
+		c.BanFile = data.Proto.Limit.Bans
+	}
+	if data.Proto.TLS.Port != 0 {
+		c.TLSPort = data.Proto.TLS.Port
+	}
+	if data.Proto.TLS.Cert != "" {
+		c.TLSCert = data.Proto.TLS.Cert
+	}
+	if data.Proto.TLS.Key != "" {
+		c.TLSKey = data.Proto.TLS.Key
+	}
 	c.Database = data.Database.File
+	if data.Database.Retention != "" {
+		c.Retention = data.Database.Retention
+	}
+	if data.Database.Days != 0 {
+		c.RetentionDays = data.Database.Days
+	}
+	switch c.Retention {
+	case RETAIN_FOREVER, RETAIN_DAYS, RETAIN_PRACTICE:
+	default:
+		return nil, fmt.Errorf("unknown retention policy %q", c.Retention)
+	}
+	c.BoardRules, err = kgp.ParseRules(data.Game.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if data.Game.EarlyWin != nil {
+		c.BoardRules.NoEarlyWin = !*data.Game.EarlyWin
+	}
+	if data.Game.Mode != "" {
+		c.Mode = data.Game.Mode
+	}
+	switch c.Mode {
+	case MODE_RANDOM, MODE_DUPLICATE:
+	default:
+		return nil, fmt.Errorf("unknown scheduling mode %q", c.Mode)
+	}
+	c.Seed = data.Game.Seed
+	if data.Game.Opening.Plies != 0 {
+		c.OpeningPlies = data.Game.Opening.Plies
+	}
+	if data.Game.Opening.Depth != 0 {
+		c.OpeningDepth = data.Game.Opening.Depth
+	}
+	if data.Game.Opening.Margin != 0 {
+		c.OpeningMargin = data.Game.Opening.Margin
+	}
 	c.MoveTimeout = time.Duration(data.Game.Timeout) * time.Millisecond
+	if md.IsDefined("game", "resume") {
+		c.ResumeTimeout = time.Duration(data.Game.Resume) * time.Millisecond
+	}
+	if md.IsDefined("game", "shutdown") {
+		c.DrainTimeout = time.Duration(data.Game.Drain) * time.Millisecond
+	}
 	c.WebInterface = data.Web.Enabled
 	c.About = data.Web.About
-	c.WebPort = uint(data.Proto.Port)
-	data.Game.Open.Init = c.BoardInit
-	data.Game.Open.Size = c.BoardSize
-	for _, d := range data.Game.Open.Bots {
-		if _, ok := c.BotTypes[d]; !ok {
-			c.BotTypes[d] = 0
+	if data.Web.Port != 0 {
+		c.WebPort = data.Web.Port
+	}
+	if data.Game.Open.Init != 0 {
+		c.BoardInit = data.Game.Open.Init
+	}
+	if data.Game.Open.Size != 0 {
+		c.BoardSize = data.Game.Open.Size
+	}
+	var boards []Board
+	for _, b := range data.Game.Open.Boards {
+		if b.Weight == 0 {
+			b.Weight = 1
+		}
+		boards = append(boards, b)
+	}
+	for _, size := range data.Game.Open.Sizes {
+		for _, init := range data.Game.Open.Stones {
+			boards = append(boards, Board{
+				Size:   size,
+				Init:   init,
+				Weight: 1,
+			})
+		}
+	}
+	for _, b := range boards {
+		if b.Size == 0 || b.Init == 0 {
+			return nil, fmt.Errorf("invalid board configuration %s", b)
 		}
-		c.BotTypes[d]++
+	}
+	if len(boards) > 0 {
+		c.BoardSet = boards
+	}
+	if data.Game.Open.Bots != nil {
+		c.Bots = []Bot{}
+	}
+	names := make(map[string]struct{})
+	for _, v := range data.Game.Open.Bots {
+		b, err := parseBot(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bot %v: %w", v, err)
+		}
+		if _, err := b.Make(kgp.KALAH); err != nil {
+			return nil, fmt.Errorf("invalid bot %s: %w", b, err)
+		}
+		if _, ok := names[b.Name]; ok {
+			return nil, fmt.Errorf("the name of the bot %s is not unique", b)
+		}
+		names[b.Name] = struct{}{}
+		c.Bots = append(c.Bots, b)
 	}
 
 	return &c, nil
@@ -84,8 +197,11 @@ func Open(name string, debug bool) (*Conf, error) {
 	defer file.Close()
 
 	c, err := load(file, debug)
+	if err != nil {
+		return nil, err
+	}
 	c.Play = make(chan *kgp.Game, 1)
-	return c, err
+	return c, nil
 }
 
 // Return a reference to the default configuration
@@ -102,16 +218,34 @@ func (c *Conf) Dump(wr io.Writer) error {
 	var data conf
 
 	data.Database.File = c.Database
+	data.Database.Retention = c.Retention
+	data.Database.Days = c.RetentionDays
 	data.Proto.Ping = c.Ping
 	data.Proto.Timeout = uint(c.TCPTimeout / time.Millisecond)
+	data.Proto.Reconnect = uint(c.ReconnectTimeout / time.Millisecond)
 	data.Proto.Port = uint(c.TCPPort)
+	data.Proto.TLS.Port = c.TLSPort
+	data.Proto.Limit.IP = c.MaxConnIP
+	data.Proto.Limit.Token = c.MaxConnToken
+	data.Proto.Limit.Rate = c.CommandRate
+	data.Proto.Limit.Burst = c.CommandBurst
+	data.Proto.Limit.Bans = c.BanFile
+	data.Proto.TLS.Cert = c.TLSCert
+	data.Proto.TLS.Key = c.TLSKey
 	data.Game.Timeout = uint(c.MoveTimeout / time.Millisecond)
+	data.Game.Resume = uint(c.ResumeTimeout / time.Millisecond)
+	data.Game.Drain = uint(c.DrainTimeout / time.Millisecond)
 	data.Game.Open.Init = c.BoardInit
 	data.Game.Open.Size = c.BoardSize
-	for d, n := range c.BotTypes {
-		for i := uint(0); i < n; i++ {
-			data.Game.Open.Bots = append(data.Game.Open.Bots, d)
-		}
+	data.Game.Open.Boards = c.BoardSet
+	data.Game.Rules = c.BoardRules.String()
+	data.Game.Mode = c.Mode
+	data.Game.Seed = c.Seed
+	data.Game.Opening.Plies = c.OpeningPlies
+	data.Game.Opening.Depth = c.OpeningDepth
+	data.Game.Opening.Margin = c.OpeningMargin
+	for _, b := range c.Bots {
+		data.Game.Open.Bots = append(data.Game.Open.Bots, b.table())
 	}
 	data.Web.Enabled = c.WebInterface
 	data.Web.About = c.About
@@ -0,0 +1,117 @@
+// Configuration Loading Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package conf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadLimits(t *testing.T) {
+	c, err := load(strings.NewReader("[proto]\nport = 2671\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConnIP != defaultConfig.MaxConnIP ||
+		c.MaxConnToken != defaultConfig.MaxConnToken ||
+		c.CommandRate != defaultConfig.CommandRate ||
+		c.CommandBurst != defaultConfig.CommandBurst {
+		t.Errorf("Missing limits did not default to %d/%d/%d/%d, got %d/%d/%d/%d",
+			defaultConfig.MaxConnIP, defaultConfig.MaxConnToken,
+			defaultConfig.CommandRate, defaultConfig.CommandBurst,
+			c.MaxConnIP, c.MaxConnToken, c.CommandRate, c.CommandBurst)
+	}
+
+	c, err = load(strings.NewReader("[proto.limit]\nip = 1\ntoken = 2\nrate = 3\nburst = 4\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConnIP != 1 || c.MaxConnToken != 2 || c.CommandRate != 3 || c.CommandBurst != 4 {
+		t.Errorf("Expected the limits 1/2/3/4, got %d/%d/%d/%d",
+			c.MaxConnIP, c.MaxConnToken, c.CommandRate, c.CommandBurst)
+	}
+
+	// An explicit zero disables a limit
+	c, err = load(strings.NewReader("[proto.limit]\nip = 0\ntoken = 0\nrate = 0\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConnIP != 0 || c.MaxConnToken != 0 || c.CommandRate != 0 {
+		t.Errorf("Expected the limits to be disabled, got %d/%d/%d",
+			c.MaxConnIP, c.MaxConnToken, c.CommandRate)
+	}
+}
+
+func TestLoadResume(t *testing.T) {
+	c, err := load(strings.NewReader("[game]\ntimeout = 5000\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ResumeTimeout != defaultConfig.ResumeTimeout {
+		t.Errorf("Missing resume timeout did not default to %s, got %s",
+			defaultConfig.ResumeTimeout, c.ResumeTimeout)
+	}
+
+	c, err = load(strings.NewReader("[game]\nresume = 0\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ResumeTimeout != 0 {
+		t.Errorf("Expected resuming to be disabled, got %s", c.ResumeTimeout)
+	}
+}
+
+func TestLoadReconnect(t *testing.T) {
+	c, err := load(strings.NewReader("[proto]\nport = 2671\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ReconnectTimeout != defaultConfig.ReconnectTimeout {
+		t.Errorf("Missing reconnect timeout did not default to %s, got %s",
+			defaultConfig.ReconnectTimeout, c.ReconnectTimeout)
+	}
+
+	c, err = load(strings.NewReader("[proto]\nreconnect = 0\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ReconnectTimeout != 0 {
+		t.Errorf("Expected reconnecting to be disabled, got %s", c.ReconnectTimeout)
+	}
+}
+
+func TestLoadDrain(t *testing.T) {
+	c, err := load(strings.NewReader("[game]\ntimeout = 5000\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DrainTimeout != defaultConfig.DrainTimeout {
+		t.Errorf("Missing shutdown timeout did not default to %s, got %s",
+			defaultConfig.DrainTimeout, c.DrainTimeout)
+	}
+
+	c, err = load(strings.NewReader("[game]\nshutdown = 0\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DrainTimeout != 0 {
+		t.Errorf("Expected draining to be disabled, got %s", c.DrainTimeout)
+	}
+}
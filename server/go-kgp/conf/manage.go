@@ -25,6 +25,7 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"go-kgp"
 )
@@ -51,6 +52,8 @@ type DatabaseManager interface {
 	QueryUserToken(context.Context, string) *kgp.User
 	QueryGames(context.Context, int, chan<- *kgp.Game, int)
 	QueryGame(context.Context, int, chan<- *kgp.Game, chan<- *kgp.Move)
+	QueryOngoing(context.Context, chan<- *kgp.Game)
+	QueryLeaderboard(context.Context, kgp.Mancala, uint, uint, chan<- *kgp.Standing, int)
 
 	// Store interface
 	SaveMove(context.Context, *kgp.Move)
@@ -83,17 +86,21 @@ func (c *Conf) Start() {
 	}
 	c.run = true
 
-	// Catch an interrupt request...
+	// Catch an interrupt or termination request...
 	intr := make(chan os.Signal, 1)
-	signal.Notify(intr, os.Interrupt)
-	<-intr
-	c.Debug.Println("Caught interrupt")
-
-	// ...and request all managers to shut down.
+	signal.Notify(intr, os.Interrupt, syscall.SIGTERM)
+	sig := <-intr
+	c.Log.Printf("Caught %s, shutting down", sig)
+	signal.Stop(intr)
+
+	// ...and request all managers to shut down, in the reverse
+	// order they were registered in.  Managers that were
+	// registered early can therefore rely on later managers
+	// having already shut down.
 	c.Debug.Println("Waiting for managers to shutdown...")
-	for _, m := range c.man {
-		c.Debug.Printf("Shutting %s down", m)
-		m.Shutdown()
+	for i := len(c.man) - 1; i >= 0; i-- {
+		c.Debug.Printf("Shutting %s down", c.man[i])
+		c.man[i].Shutdown()
 	}
 	c.Debug.Println("Shutting down")
 }
@@ -6,5 +6,13 @@ CREATE TABLE IF NOT EXISTS game (
 	init INTEGER CHECK(init > 0) NOT NULL,
 	north REFERENCES agent(id) ON DELETE CASCADE,
 	south REFERENCES agent(id) ON DELETE CASCADE,
-	state TEXT CHECK(state IN ("o", "nw", "sw", "u", "nr", "sr", "a"))
+	state TEXT CHECK(state IN ("o", "nw", "sw", "u", "nr", "sr", "a")),
+	expired BOOLEAN NOT NULL DEFAULT FALSE,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	board TEXT,		-- Current state of an ongoing game
+	current BOOLEAN,	-- Side to move, see Side in board.go
+	rules TEXT,		-- Rule variant, see Rules in rules.go
+	mancala TEXT,		-- Game played, see Mancala in oware.go
+	opening REFERENCES opening(id), -- Position the game started from
+	seed INTEGER		-- Seed for random decisions, see Game in common.go
 );
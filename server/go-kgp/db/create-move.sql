@@ -6,5 +6,8 @@ CREATE TABLE IF NOT EXISTS move (
 	side BOOLEAN,		-- See Side in board.go
 	game REFERENCES game(id) ON DELETE CASCADE,
 	played DATETIME,
-	choice INT
+	choice INT,
+	board TEXT,		-- State after the move
+	think INTEGER,		-- Milliseconds the agent took
+	source TEXT CHECK(source IN ("c", "a", "f"))
 );
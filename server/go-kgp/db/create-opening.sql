@@ -0,0 +1,9 @@
+-- -*- sql-product: sqlite; -*-
+
+CREATE TABLE IF NOT EXISTS opening (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	board TEXT NOT NULL,	-- Position the games start from
+	current BOOLEAN NOT NULL, -- Side to move, see Side in board.go
+	plies INTEGER NOT NULL,	-- Number of random moves
+	eval INTEGER NOT NULL	-- Evaluation for the side to move
+);
@@ -0,0 +1,11 @@
+-- -*- sql-product: sqlite; -*-
+
+CREATE TABLE IF NOT EXISTS rating (
+       agent REFERENCES agent(id) ON DELETE CASCADE,
+       mancala TEXT NOT NULL,
+       size INTEGER NOT NULL,
+       init INTEGER NOT NULL,
+       rating REAL NOT NULL,
+       games INTEGER NOT NULL,
+       PRIMARY KEY (agent, mancala, size, init)
+);
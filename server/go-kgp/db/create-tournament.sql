@@ -3,5 +3,6 @@
 CREATE TABLE IF NOT EXISTS tournament (
        id INTEGER PRIMARY KEY AUTOINCREMENT,
        name TEXT,
-       start TIMESTAMP
+       start TIMESTAMP,
+       seed INTEGER
 );
@@ -28,9 +28,11 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"math"
 	"os"
 	"os/signal"
 	"path"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -42,6 +44,13 @@ import (
 	"go-kgp/game"
 )
 
+const (
+	// The rating of an agent that has not finished a game yet
+	RATING_INIT = 1500
+	// The maximal change of a rating after a single game
+	RATING_K = 32
+)
+
 //go:embed *.sql
 var sql_dir embed.FS
 
@@ -75,8 +84,13 @@ func (u *user) Alive() bool {
 	return false // users aren't live agents
 }
 
+// Register a new tournament, along with the seed of the scheduler
+//
+// The seed is only known after the scheduler has started (see
+// conf.Random).
 func (db *db) RegisterTournament(ctx context.Context, name string) int64 {
-	res, err := db.commands["insert-tournament"].ExecContext(ctx, name)
+	res, err := db.commands["insert-tournament"].ExecContext(ctx,
+		name, db.conf.Seed)
 	if err != nil {
 		db.conf.Log.Fatal(err)
 	}
@@ -193,23 +207,37 @@ func (db *db) QueryGame(ctx context.Context, gid int, gc chan<- *kgp.Game, mc ch
 
 	for rows.Next() {
 		var (
-			m    = &kgp.Move{}
-			side bool
+			m     = &kgp.Move{}
+			side  bool
+			board *string
+			think *int64
 		)
-		err = rows.Scan(&side, &m.Comment, &m.Choice, &m.Stamp)
+		err = rows.Scan(&side, &m.Comment, &m.Choice, &m.Stamp,
+			&board, &think, &m.Source)
 		if err != nil {
 			db.conf.Log.Print(err)
 			return
 		}
 		m.Agent = g.Player(kgp.Side(side))
+		if think != nil {
+			m.Think = time.Duration(*think) * time.Millisecond
+		}
 
-		if next, repeat := game.MoveCopy(g, m); !repeat {
-			db.conf.Log.Printf("Illegal move %d on %s", m.Choice, g.State)
+		// Moves that were recorded without the resulting
+		// board state have to be replayed.
+		if board != nil {
+			m.State, err = kgp.Parse(*board)
+			if err != nil {
+				db.conf.Log.Print(err)
+				return
+			}
+		} else if next, legal := game.MoveCopy(g, m); !legal {
+			db.conf.Log.Printf("Illegal move %d on %s", m.Choice, g.Board)
 			break
 		} else {
 			g = next
+			m.State = g.Board.Copy()
 		}
-		m.State = g.Board.Copy()
 
 		mc <- m
 	}
@@ -222,6 +250,11 @@ func (db *db) scanGame(ctx context.Context, scan func(dest ...interface{}) error
 	var (
 		nid, sid   int
 		size, init uint
+		rules      sql.NullString
+		mancala    kgp.Mancala
+		oid        sql.NullInt64
+		opening    sql.NullString
+		current    sql.NullBool
 	)
 
 	game = &kgp.Game{}
@@ -230,11 +263,36 @@ func (db *db) scanGame(ctx context.Context, scan func(dest ...interface{}) error
 		&size, &init,
 		&nid, &sid,
 		&game.State,
-		&game.MoveCount)
+		&game.MoveCount,
+		&game.Expired,
+		&rules,
+		&mancala,
+		&oid, &opening, &current,
+		&game.Seed)
+	if err != nil {
+		return
+	}
+	if opening.Valid {
+		// Games that started from an opening are replayed
+		// from the opening instead of the initial board
+		game.Opening = &kgp.Opening{
+			Id:      oid.Int64,
+			Current: kgp.Side(current.Bool),
+		}
+		game.Opening.Board, err = kgp.Parse(opening.String)
+		if err != nil {
+			return
+		}
+		game.Board = game.Opening.Board.Copy()
+		game.Current = game.Opening.Current
+	} else {
+		game.Board = kgp.MakeBoard(size, init)
+	}
+	game.Board.SetMancala(mancala)
+	err = db.scanRules(game.Board, rules)
 	if err != nil {
 		return
 	}
-	game.Board = kgp.MakeBoard(size, init)
 
 	var south, north *kgp.User
 	south, err = db.queryUser(ctx, sid)
@@ -252,6 +310,22 @@ func (db *db) scanGame(ctx context.Context, scan func(dest ...interface{}) error
 	return
 }
 
+// Apply the RULES stored for a game to BOARD
+//
+// Games that were stored before rule variants were introduced have no
+// rules, and were played using the default rules.
+func (db *db) scanRules(board *kgp.Board, rules sql.NullString) error {
+	if !rules.Valid {
+		return nil
+	}
+	r, err := kgp.ParseRules(rules.String)
+	if err != nil {
+		return err
+	}
+	board.SetRules(r)
+	return nil
+}
+
 func (db *db) QueryGames(ctx context.Context, aid int, c chan<- *kgp.Game, page int) {
 	defer close(c)
 
@@ -288,6 +362,56 @@ func (db *db) QueryGames(ctx context.Context, aid int, c chan<- *kgp.Game, page
 	}
 }
 
+func (db *db) QueryOngoing(ctx context.Context, c chan<- *kgp.Game) {
+	defer close(c)
+
+	rows, err := db.queries["select-ongoing"].QueryContext(ctx)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			db.conf.Log.Print(err)
+		}
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			game         = &kgp.Game{State: kgp.ONGOING}
+			north, south kgp.User
+			size, init   uint
+			board        string
+			rules        sql.NullString
+			mancala      kgp.Mancala
+		)
+
+		err = rows.Scan(&game.Id, &size, &init, &board, &game.Current,
+			&rules, &mancala, &game.Seed,
+			&north.Id, &north.Token, &south.Id, &south.Token)
+		if err != nil {
+			db.conf.Log.Print(err)
+			return
+		}
+		game.Board, err = kgp.Parse(board)
+		if err != nil {
+			db.conf.Log.Print(err)
+			continue
+		}
+		err = db.scanRules(game.Board, rules)
+		if err != nil {
+			db.conf.Log.Print(err)
+			continue
+		}
+		game.Board.SetMancala(mancala)
+		game.North = (*user)(&north)
+		game.South = (*user)(&south)
+
+		c <- game
+	}
+	if err = rows.Err(); err != nil {
+		db.conf.Log.Print(err)
+	}
+}
+
 func (db *db) QueryUsers(ctx context.Context, c chan<- *kgp.User, page int) {
 	defer close(c)
 	rows, err := db.queries["select-agents"].QueryContext(ctx, page, 50)
@@ -320,6 +444,45 @@ func (db *db) QueryUsers(ctx context.Context, c chan<- *kgp.User, page int) {
 	}
 }
 
+func (db *db) QueryLeaderboard(ctx context.Context, m kgp.Mancala, size, init uint, c chan<- *kgp.Standing, page int) {
+	defer close(c)
+	rows, err := db.queries["select-leaderboard"].QueryContext(ctx,
+		m.String(), size, init, page, 50)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			db.conf.Log.Print(err)
+		}
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			u kgp.User
+			s = kgp.Standing{User: &u}
+		)
+
+		err = rows.Scan(
+			&u.Id,
+			&u.Name,
+			&u.Author,
+			&s.Won,
+			&s.Drawn,
+			&s.Lost,
+			&s.Rating)
+		if err != nil {
+			db.conf.Log.Print(err)
+			return
+		}
+
+		c <- &s
+	}
+	if err = rows.Err(); err != nil {
+		db.conf.Log.Print(err)
+		return
+	}
+}
+
 func (db *db) SaveGame(ctx context.Context, game *kgp.Game) {
 	tx, err := db.write.BeginTx(ctx, nil)
 	if err != nil {
@@ -337,6 +500,9 @@ func (db *db) SaveGame(ctx context.Context, game *kgp.Game) {
 	if !db.saveGame(ctx, tx, game) {
 		return
 	}
+	if !db.rate(ctx, tx, game) {
+		return
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -348,11 +514,33 @@ func (db *db) saveGame(ctx context.Context, tx *sql.Tx, game *kgp.Game) bool {
 	if game.Id == 0 {
 		north, south := game.North.User(), game.South.User()
 
+		var opening *int64
+		if o := game.Opening; o != nil {
+			if o.Id == 0 {
+				res, err := tx.Stmt(db.commands["insert-opening"]).ExecContext(ctx,
+					o.Board.String(), o.Current, o.Plies, o.Eval)
+				if err != nil {
+					db.conf.Log.Print(err)
+					return false
+				}
+				o.Id, err = res.LastInsertId()
+				if err != nil {
+					db.conf.Log.Print(err)
+					return false
+				}
+			}
+			opening = &o.Id
+		}
+
 		size, init := game.Board.Type()
 		db.conf.Debug.Printf("Saving game with SID %d and NID %d",
 			south.Id, north.Id)
 		res, err := tx.Stmt(db.commands["insert-game"]).ExecContext(ctx,
-			size, init, north.Id, south.Id, game.State.String())
+			size, init, north.Id, south.Id, game.State.String(),
+			game.Board.String(), game.Current,
+			game.Board.Rules().String(),
+			game.Board.Mancala().String(),
+			opening, game.Seed)
 		if err != nil {
 			db.conf.Log.Print(err)
 			return false
@@ -366,7 +554,64 @@ func (db *db) saveGame(ctx context.Context, tx *sql.Tx, game *kgp.Game) bool {
 		game.Id = uint64(id)
 	} else {
 		_, err := tx.Stmt(db.commands["update-game"]).ExecContext(ctx,
-			game.State.String(), game.Id)
+			game.State.String(), game.Board.String(), game.Current,
+			game.Id)
+		if err != nil {
+			db.conf.Log.Print(err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// Update the ratings of both players, if GAME has been decided
+//
+// Ratings are tracked separately for every game and board
+// configuration, using the Elo rating system.
+func (db *db) rate(ctx context.Context, tx *sql.Tx, game *kgp.Game) bool {
+	var score float64 // the score of the south player
+	switch game.State {
+	case kgp.SOUTH_WON, kgp.NORTH_RESIGNED:
+		score = 1
+	case kgp.NORTH_WON, kgp.SOUTH_RESIGNED:
+		score = 0
+	case kgp.UNDECIDED:
+		score = 0.5
+	default:
+		return true
+	}
+
+	north, south := game.North.User(), game.South.User()
+	if north == nil || south == nil || north.Id == 0 || south.Id == 0 ||
+		north.Id == south.Id {
+		return true
+	}
+
+	var (
+		size, init uint
+		mancala    string
+		nr, sr     float64
+	)
+	err := db.queries["select-ratings"].QueryRowContext(ctx,
+		game.Id, RATING_INIT).Scan(&size, &init, &mancala, &nr, &sr)
+	if err != nil {
+		db.conf.Log.Print(err)
+		return !errors.Is(err, sql.ErrNoRows)
+	}
+
+	// The expected score of the south player
+	expected := 1 / (1 + math.Pow(10, (nr-sr)/400))
+	delta := RATING_K * (score - expected)
+	for _, r := range []struct {
+		id     int64
+		rating float64
+	}{
+		{south.Id, sr + delta},
+		{north.Id, nr - delta},
+	} {
+		_, err = tx.Stmt(db.commands["insert-rating"]).ExecContext(ctx,
+			r.id, mancala, size, init, r.rating)
 		if err != nil {
 			db.conf.Log.Print(err)
 			return false
@@ -461,7 +706,10 @@ func (db *db) SaveMove(ctx context.Context, move *kgp.Move) {
 		game.Side(move.Agent),
 		move.Choice,
 		move.Comment,
-		move.Stamp)
+		move.Stamp,
+		move.State.String(),
+		move.Think.Milliseconds(),
+		move.Source.String())
 	if err != nil {
 		db.conf.Log.Print(err)
 		return
@@ -541,6 +789,36 @@ func (db *db) DrawGraph(ctx context.Context, w io.Writer) error {
 	return nil
 }
 
+// Delete the moves of all games that have exceeded the retention period
+func (db *db) expire(ctx context.Context) error {
+	if db.conf.Retention == conf.RETAIN_FOREVER {
+		return nil
+	}
+
+	tx, err := db.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	cutoff := fmt.Sprintf("-%d days", db.conf.RetentionDays)
+	practice := db.conf.Retention == conf.RETAIN_PRACTICE
+	res, err := tx.Stmt(db.commands["update-expire"]).ExecContext(ctx,
+		cutoff, practice)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		db.conf.Debug.Printf("Expiring the moves of %d games", n)
+	}
+	_, err = tx.Stmt(db.commands["delete-moves"]).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (db *db) Start() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGUSR1)
@@ -552,7 +830,10 @@ func (db *db) Start() {
 			// https://www.sqlite.org/lang_vacuum.html
 			_, err = db.write.Exec("VACUUM;")
 		case <-tick.C:
-			db.commands["delete-moves"].Exec()
+			err = db.expire(context.Background())
+			if err != nil {
+				break
+			}
 			// https://www.sqlite.org/pragma.html#pragma_optimize
 			_, err = db.write.Exec("PRAGMA optimize;")
 		}
@@ -627,6 +908,21 @@ func Prepare(config *conf.Conf) {
 	if err != nil {
 		fatal(err)
 	}
+	// Tables have to be created and migrated before any statement
+	// that refers to them can be prepared.
+	phase := func(name string) int {
+		switch {
+		case strings.HasPrefix(name, "create-"), strings.HasPrefix(name, "run-"):
+			return 0
+		case strings.HasPrefix(name, "migrate-"):
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return phase(entries[i].Name()) < phase(entries[j].Name())
+	})
 	queries := make(map[string]*sql.Stmt)
 	commands := make(map[string]*sql.Stmt)
 	for _, entry := range entries {
@@ -643,6 +939,16 @@ func Prepare(config *conf.Conf) {
 		if strings.HasPrefix(base, "create-") || strings.HasPrefix(base, "run-") {
 			_, err = write.Exec(string(data))
 			config.Debug.Printf("Executed query %v", base)
+		} else if strings.HasPrefix(base, "migrate-") {
+			// Migrations extend tables that might have
+			// been created by an older version, and fail
+			// if the change has already been applied.
+			_, err = write.Exec(string(data))
+			if err == nil {
+				config.Debug.Printf("Executed migration %v", base)
+			} else if strings.Contains(err.Error(), "duplicate column name") {
+				err = nil
+			}
 		} else {
 			query := strings.TrimSuffix(base, ".sql")
 			if strings.HasPrefix(query, "select-") {
@@ -662,6 +968,11 @@ func Prepare(config *conf.Conf) {
 		panic("No queries loaded")
 	}
 
+	_, err = commands["update-abort-ongoing"].Exec(config.ResumeTimeout == 0)
+	if err != nil {
+		fatal(err)
+	}
+
 	var man conf.DatabaseManager = &db{
 		read:     read,
 		write:    write,
@@ -0,0 +1,261 @@
+// Database Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go-kgp"
+	"go-kgp/conf"
+)
+
+// Prepare an empty in-memory database for the test T
+func testDB(t *testing.T, modify func(*conf.Conf)) *db {
+	c := *conf.Default(false)
+	c.Database = fmt.Sprintf("file:%s?mode=memory&cache=shared",
+		strings.ReplaceAll(t.Name(), "/", "-"))
+	if modify != nil {
+		modify(&c)
+	}
+	Prepare(&c)
+
+	db := c.DB.(*db)
+	t.Cleanup(db.Shutdown)
+	return db
+}
+
+// Execute the SQL statement QUERY or fail the test T
+func exec(t *testing.T, db *db, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.write.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	const (
+		practice = iota + 1
+		recent
+		tournament
+		archived
+		ongoing
+	)
+
+	for _, test := range []struct {
+		retention string
+		expired   []int
+	}{
+		{conf.RETAIN_FOREVER, nil},
+		{conf.RETAIN_DAYS, []int{practice, tournament, archived}},
+		{conf.RETAIN_PRACTICE, []int{practice}},
+	} {
+		t.Run(test.retention, func(t *testing.T) {
+			db := testDB(t, func(c *conf.Conf) {
+				c.Retention = test.retention
+				c.RetentionDays = 7
+			})
+
+			exec(t, db, `INSERT INTO agent(id, token) VALUES (1, "a"), (2, "b")`)
+			exec(t, db, `INSERT INTO tournament(id, name) VALUES (1, "t")`)
+			for _, g := range []struct {
+				id       int
+				state    string
+				archived bool
+				age      string
+			}{
+				{practice, "sw", false, "-10 days"},
+				{recent, "nw", false, "-1 days"},
+				{tournament, "u", false, "-10 days"},
+				{archived, "sw", true, "-10 days"},
+				{ongoing, "o", false, "-10 days"},
+			} {
+				exec(t, db, `INSERT INTO game(id, size, init, north, south, state, archived)
+                                             VALUES (?, 6, 6, 1, 2, ?, ?)`,
+					g.id, g.state, g.archived)
+				exec(t, db, `INSERT INTO move(game, agent, side, choice, played)
+                                             VALUES (?, 1, FALSE, 1,
+                                                     strftime("%Y-%m-%d %H:%M:%S.000000000+00:00", "now", ?))`,
+					g.id, g.age)
+			}
+			exec(t, db, `INSERT INTO score(agent, game, tournament, score)
+                                     VALUES (1, ?, 1, 1)`, tournament)
+
+			if err := db.expire(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			expired := make(map[int]bool)
+			for _, id := range test.expired {
+				expired[id] = true
+			}
+			for id := practice; id <= ongoing; id++ {
+				var (
+					flag  bool
+					moves int
+				)
+				err := db.read.QueryRow(`SELECT expired, (SELECT COUNT(*) FROM move WHERE game = ?)
+                                                         FROM game WHERE id = ?`, id, id).Scan(&flag, &moves)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if flag != expired[id] {
+					t.Errorf("Game %d: expected expired to be %t", id, expired[id])
+				}
+				if (moves == 0) != expired[id] {
+					t.Errorf("Game %d: unexpectedly has %d moves", id, moves)
+				}
+			}
+		})
+	}
+}
+
+func TestOngoing(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		board = kgp.MakeBoard(4, 3)
+		game  = &kgp.Game{
+			Board:   board,
+			North:   (*user)(&kgp.User{Token: "north"}),
+			South:   (*user)(&kgp.User{Token: "south"}),
+			State:   kgp.ONGOING,
+			Current: kgp.North,
+			Seed:    42,
+		}
+	)
+	board.Sow(kgp.South, 1)
+
+	db := testDB(t, nil)
+	db.SaveGame(ctx, game)
+	if game.Id == 0 {
+		t.Fatal("The game was not saved")
+	}
+
+	c := make(chan *kgp.Game)
+	go db.QueryOngoing(ctx, c)
+	var ongoing []*kgp.Game
+	for g := range c {
+		ongoing = append(ongoing, g)
+	}
+	if len(ongoing) != 1 {
+		t.Fatalf("Expected one ongoing game, got %d", len(ongoing))
+	}
+
+	g := ongoing[0]
+	if g.Id != game.Id {
+		t.Errorf("Expected game %d, got %d", game.Id, g.Id)
+	}
+	if g.Board.String() != board.String() {
+		t.Errorf("Expected board %s, got %s", board, g.Board)
+	}
+	if g.Current != game.Current {
+		t.Errorf("Expected %s to move, got %s", game.Current, g.Current)
+	}
+	if g.Seed != game.Seed {
+		t.Errorf("Expected seed %d, got %d", game.Seed, g.Seed)
+	}
+	if g.North.User().Token != "north" || g.South.User().Token != "south" {
+		t.Error("The agents were not restored")
+	}
+}
+
+func TestAbortOngoing(t *testing.T) {
+	for _, resume := range []bool{false, true} {
+		t.Run(fmt.Sprint(resume), func(t *testing.T) {
+			var (
+				ctx     = context.Background()
+				timeout time.Duration
+			)
+			if resume {
+				timeout = time.Minute
+			}
+
+			db := testDB(t, nil)
+			exec(t, db, `INSERT INTO agent(id, token) VALUES (1, "a"), (2, "b")`)
+			exec(t, db, `INSERT INTO game(id, size, init, north, south, state, board, current)
+                                     VALUES (1, 6, 6, 1, 2, "o", ?, FALSE),
+                                            (2, 6, 6, 1, 2, "o", NULL, NULL)`,
+				kgp.MakeBoard(6, 6).String())
+
+			// Opening the same database again has the effect
+			// of a restart.
+			restart := testDB(t, func(c *conf.Conf) {
+				c.Database = db.conf.Database
+				c.ResumeTimeout = timeout
+			})
+
+			c := make(chan *kgp.Game)
+			go restart.QueryOngoing(ctx, c)
+			n := 0
+			for range c {
+				n++
+			}
+			if resume && n != 1 {
+				t.Errorf("Expected the game with a board to be resumed")
+			} else if !resume && n != 0 {
+				t.Errorf("Expected no games to be resumed, got %d", n)
+			}
+
+			var aborted int
+			err := restart.read.QueryRow(`SELECT COUNT(*) FROM game WHERE state = "a"`).Scan(&aborted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expect := 2 - n; aborted != expect {
+				t.Errorf("Expected %d aborted games, got %d", expect, aborted)
+			}
+		})
+	}
+}
+
+func TestMigrateBotTokens(t *testing.T) {
+	db := testDB(t, nil)
+	exec(t, db, `INSERT INTO agent(id, token, name) VALUES
+                     (1, "-mm2", "MinMax-2"),
+                     (2, "old-mm4", "MinMax-4"),
+                     (3, "new-mm4", "MinMax-4"),
+                     (4, "secret", "MinMax-6"),
+                     (5, "x-mm8", "MinMax-8 (mobility)")`)
+
+	// Opening the same database again runs the migrations
+	restart := testDB(t, func(c *conf.Conf) {
+		c.Database = db.conf.Database
+	})
+
+	for id, token := range map[int]string{
+		1: "bot:MinMax-2",
+		2: "old-mm4",
+		3: "bot:MinMax-4",
+		4: "secret",
+		5: "x-mm8",
+	} {
+		var actual string
+		err := restart.read.QueryRow(`SELECT token FROM agent WHERE id = ?`, id).Scan(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != token {
+			t.Errorf("Expected agent %d to have the token %q, got %q", id, token, actual)
+		}
+	}
+}
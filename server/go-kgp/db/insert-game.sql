@@ -1,4 +1,5 @@
 -- -*- sql-product: sqlite; -*-
 
-INSERT INTO game(size, init, north, south, state)
-VALUES (?, ?, ?, ?, ?);
+INSERT INTO game(size, init, north, south, state, board, current, rules,
+                 mancala, opening, seed)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
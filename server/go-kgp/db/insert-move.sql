@@ -1,4 +1,4 @@
 -- -*- sql-product: sqlite; -*-
 
-INSERT INTO move(game, agent, side, choice, comment, played)
-VALUES (?, ?, ?, ?, ?, ?);
+INSERT INTO move(game, agent, side, choice, comment, played, board, think, source)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+INSERT INTO opening(board, current, plies, eval)
+VALUES (?, ?, ?, ?);
@@ -0,0 +1,6 @@
+-- -*- sql-product: sqlite; -*-
+
+INSERT INTO rating(agent, mancala, size, init, rating, games)
+VALUES (?1, ?2, ?3, ?4, ?5, 1)
+ON CONFLICT(agent, mancala, size, init)
+DO UPDATE SET rating = ?5, games = games + 1;
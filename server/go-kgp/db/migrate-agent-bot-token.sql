@@ -0,0 +1,16 @@
+-- -*- sql-product: sqlite; -*-
+
+-- MinMax bots used to be identified by the token "<nonce>-mm<depth>",
+-- where the nonce was taken from the environment, and are now
+-- identified by their name (see bot.Token).  If a bot was recorded
+-- under multiple nonces, the most recent entry is preserved.
+UPDATE agent
+SET token = 'bot:' || name
+WHERE name GLOB 'MinMax-[0-9]*'
+  AND substr(name, 8) NOT GLOB '*[^0-9]*'
+  AND token GLOB '*-mm' || substr(name, 8)
+  AND id = (SELECT MAX(a.id) FROM agent AS a
+            WHERE a.name = agent.name
+              AND a.token GLOB '*-mm' || substr(a.name, 8))
+  AND NOT EXISTS (SELECT 1 FROM agent AS a
+                  WHERE a.token = 'bot:' || agent.name);
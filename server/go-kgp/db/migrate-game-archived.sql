@@ -0,0 +1,6 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Games that should not be deleted when using the "practice"
+-- retention policy.  There is no interface to archive a game, the
+-- flag has to be set manually.
+ALTER TABLE game ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The current board, used to resume ongoing games
+ALTER TABLE game ADD COLUMN board TEXT;
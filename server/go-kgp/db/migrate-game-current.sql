@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The side to move, used to resume ongoing games
+ALTER TABLE game ADD COLUMN current BOOLEAN;
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Games where the moves have been deleted
+ALTER TABLE game ADD COLUMN expired BOOLEAN NOT NULL DEFAULT FALSE;
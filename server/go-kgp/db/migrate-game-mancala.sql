@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The game of the Mancala family that was played
+ALTER TABLE game ADD COLUMN mancala TEXT;
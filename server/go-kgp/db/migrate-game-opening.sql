@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The opening a game started from, if any
+ALTER TABLE game ADD COLUMN opening REFERENCES opening(id);
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The rule variant a game is played with
+ALTER TABLE game ADD COLUMN rules TEXT;
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The seed for all random decisions made during a game
+ALTER TABLE game ADD COLUMN seed INTEGER;
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Board state after a move was made
+ALTER TABLE move ADD COLUMN board TEXT;
@@ -0,0 +1,5 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Was the move chosen by the agent ("c"), made automatically ("a") or
+-- randomly selected as a fallback ("f")
+ALTER TABLE move ADD COLUMN source TEXT CHECK(source IN ("c", "a", "f"));
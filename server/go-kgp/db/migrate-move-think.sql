@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Time in milliseconds an agent needed to decide on a move
+ALTER TABLE move ADD COLUMN think INTEGER;
@@ -0,0 +1,4 @@
+-- -*- sql-product: sqlite; -*-
+
+-- The seed for all scheduling decisions made during a tournament
+ALTER TABLE tournament ADD COLUMN seed INTEGER;
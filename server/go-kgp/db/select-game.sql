@@ -1,6 +1,9 @@
 -- -*- sql-product: sqlite; -*-
 
 SELECT game.id, game.size, game.init, game.north, game.south, game.state,
-       COUNT(move.game)
+       COUNT(move.game), game.expired, game.rules,
+       game.mancala, opening.id, opening.board, opening.current,
+       IFNULL(game.seed, 0)
 FROM game LEFT JOIN move ON game.id = move.game
+LEFT JOIN opening ON opening.id = game.opening
 WHERE game.id = ?;
@@ -1,10 +1,14 @@
 -- -*- sql-product: sqlite; -*-
 
 SELECT game.id, game.size, game.init, game.north, game.south, game.state,
-       COUNT(move.game)
-FROM game INNER JOIN move ON game.id = move.game
+       COUNT(move.game), game.expired, game.rules,
+       game.mancala, opening.id, opening.board, opening.current,
+       IFNULL(game.seed, 0)
+FROM game LEFT JOIN move ON game.id = move.game
+LEFT JOIN opening ON opening.id = game.opening
 WHERE game.north == ?1 OR game.south == ?1
 GROUP BY game.id
-ORDER BY MAX(move.played) DESC
+HAVING COUNT(move.game) > 0 OR game.expired
+ORDER BY MAX(move.played) DESC, game.id DESC
 LIMIT 100
 OFFSET ?2 * 100;
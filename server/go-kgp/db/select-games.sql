@@ -1,9 +1,13 @@
 -- -*- sql-product: sqlite; -*-
 
 SELECT game.id, game.size, game.init, game.north, game.south, game.state,
-       COUNT(move.game)
-FROM game INNER JOIN move ON game.id = move.game
+       COUNT(move.game), game.expired, game.rules,
+       game.mancala, opening.id, opening.board, opening.current,
+       IFNULL(game.seed, 0)
+FROM game LEFT JOIN move ON game.id = move.game
+LEFT JOIN opening ON opening.id = game.opening
 GROUP BY game.id
+HAVING COUNT(move.game) > 0 OR game.expired
 ORDER BY game.id DESC
 LIMIT 25
 OFFSET ?1 * 100;
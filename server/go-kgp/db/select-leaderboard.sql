@@ -0,0 +1,36 @@
+-- -*- sql-product: sqlite; -*-
+
+WITH result(agent, outcome) AS (
+     SELECT north, CASE state WHEN "nw" THEN "w" WHEN "sr" THEN "w"
+                              WHEN "sw" THEN "l" WHEN "nr" THEN "l"
+                              ELSE "d" END
+     FROM game
+     WHERE IFNULL(mancala, "kalah") = ?1 AND size = ?2 AND init = ?3
+       AND state IN ("nw", "sw", "nr", "sr", "u")
+     UNION ALL
+     SELECT south, CASE state WHEN "sw" THEN "w" WHEN "nr" THEN "w"
+                              WHEN "nw" THEN "l" WHEN "sr" THEN "l"
+                              ELSE "d" END
+     FROM game
+     WHERE IFNULL(mancala, "kalah") = ?1 AND size = ?2 AND init = ?3
+       AND state IN ("nw", "sw", "nr", "sr", "u")
+)
+SELECT agent.id, agent.name, agent.author,
+       SUM(result.outcome = "w") AS won,
+       SUM(result.outcome = "d") AS drawn,
+       SUM(result.outcome = "l") AS lost,
+       IFNULL(rating.rating, 0)
+FROM agent
+JOIN result ON agent.id = result.agent
+LEFT JOIN rating
+     ON rating.agent = agent.id
+    AND rating.mancala = ?1
+    AND rating.size = ?2
+    AND rating.init = ?3
+GROUP BY agent.id
+ORDER BY rating.rating IS NULL,
+         rating.rating DESC,
+         (won + drawn / 2.0) / COUNT(*) DESC,
+         COUNT(*) DESC
+LIMIT ?5
+OFFSET ?4 * ?5;
@@ -1,6 +1,12 @@
 -- -*- sql-product: sqlite; -*-
 
-SELECT side, comment, choice, played
+-- Moves that were stored before the source was recorded are
+-- classified by their comment.
+SELECT side, comment, choice, played, board, think,
+       COALESCE(source, CASE comment
+                        WHEN "[Auto-move]" THEN "a"
+                        WHEN "[random move]" THEN "f"
+                        ELSE "c" END)
 FROM move
 WHERE game = ?
 ORDER BY played;
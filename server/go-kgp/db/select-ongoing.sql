@@ -0,0 +1,9 @@
+-- -*- sql-product: sqlite; -*-
+
+SELECT game.id, game.size, game.init, game.board, game.current, game.rules,
+       game.mancala, IFNULL(game.seed, 0),
+       north.id, north.token, south.id, south.token
+FROM game
+JOIN agent AS north ON north.id = game.north
+JOIN agent AS south ON south.id = game.south
+WHERE game.state = "o" AND game.board IS NOT NULL;
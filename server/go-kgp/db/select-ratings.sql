@@ -0,0 +1,18 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Ratings of the north and south player of a game, in the
+-- configuration the game was played in
+SELECT game.size, game.init, IFNULL(game.mancala, "kalah"),
+       IFNULL(north.rating, ?2), IFNULL(south.rating, ?2)
+FROM game
+LEFT JOIN rating AS north
+     ON north.agent = game.north
+    AND north.mancala = IFNULL(game.mancala, "kalah")
+    AND north.size = game.size
+    AND north.init = game.init
+LEFT JOIN rating AS south
+     ON south.agent = game.south
+    AND south.mancala = IFNULL(game.mancala, "kalah")
+    AND south.size = game.size
+    AND south.init = game.init
+WHERE game.id = ?1;
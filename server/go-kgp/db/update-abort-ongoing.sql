@@ -0,0 +1,9 @@
+-- -*- sql-product: sqlite; -*-
+
+-- If the server stopped and the database had an ongoing game, that
+-- will not be resumed (either because resuming has been disabled, as
+-- indicated by the first argument, or because the state of the game
+-- was not recorded), we will regard it as aborted.
+UPDATE OR IGNORE game
+SET state = "a"
+WHERE state = "o" AND (?1 OR board IS NULL);
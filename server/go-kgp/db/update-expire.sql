@@ -0,0 +1,16 @@
+-- -*- sql-product: sqlite; -*-
+
+-- Mark all finished games as expired, where the last move was made
+-- before the cutoff.  If the second argument is true, tournament
+-- games and archived games are exempt.
+UPDATE game
+SET expired = TRUE
+WHERE NOT expired
+  AND state != "o"
+  AND id IN (SELECT game
+             FROM move
+             GROUP BY game
+             HAVING MAX(played) < strftime("%Y-%m-%d %H:%M:%S.000000000+00:00", "now", ?1))
+  AND NOT (?2 AND (archived OR id IN (SELECT game
+                                      FROM score
+                                      WHERE tournament IS NOT NULL)));
@@ -1,5 +1,5 @@
 -- -*- sql-product: sqlite; -*-
 
 UPDATE OR IGNORE game
-SET state = ?
+SET state = ?, board = ?, current = ?
 WHERE id = ?;
@@ -21,6 +21,8 @@ package game
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"go-kgp"
@@ -52,15 +54,62 @@ func MoveCopy(g *kgp.Game, m *kgp.Move) (*kgp.Game, bool) {
 	return c, Move(c, m)
 }
 
-func Play(g *kgp.Game, conf *conf.Conf) {
+// Play a game until it is over or CTX is cancelled
+//
+// An interrupted game is aborted.  Both agents are scheduled again
+// when the game is over.
+func Play(ctx context.Context, g *kgp.Game, conf *conf.Conf) {
+	play(ctx, g, conf)
+	schedule(conf, g.South, g.North)
+}
+
+// Play two games between A and B starting from OPENING
+//
+// A plays south in the first game and north in the second game, so
+// that both agents have to play both sides of the same position.  If
+// the first game is aborted, the second game is not played.  Both
+// games use the same SEED.
+func Duplicate(ctx context.Context, opening *kgp.Opening, a, b kgp.Agent, seed int64, conf *conf.Conf) {
+	for _, g := range []*kgp.Game{
+		{South: a, North: b, Seed: seed},
+		{South: b, North: a, Seed: seed},
+	} {
+		g.Board = opening.Board.Copy()
+		g.Current = opening.Current
+		g.Opening = opening
+		play(ctx, g, conf)
+		if g.State == kgp.ABORTED {
+			break
+		}
+	}
+	schedule(conf, a, b)
+}
+
+// Schedule all non-nil AGENTS for new games
+func schedule(conf *conf.Conf, agents ...kgp.Agent) {
+	for _, a := range agents {
+		if a != nil {
+			conf.GM.Schedule(a)
+		}
+	}
+}
+
+func play(ctx context.Context, g *kgp.Game, conf *conf.Conf) {
 	dbg := conf.Debug.Printf
 	bg := context.Background()
+	size, init := g.Board.Type()
 
 	g.State = kgp.ONGOING
 	conf.DB.SaveGame(bg, g)
 	for !g.Board.Over() {
 		var m *kgp.Move
 
+		if ctx.Err() != nil {
+			dbg("Game %d: interrupted", g.Id)
+			g.State = kgp.ABORTED
+			goto save
+		}
+
 		count, last := g.Board.Moves(g.Current)
 		dbg("Game %d: %s has %d moves",
 			g.Id, g.State.String(), count)
@@ -77,6 +126,7 @@ func Play(g *kgp.Game, conf *conf.Conf) {
 				Choice:  last,
 				Game:    g,
 				Stamp:   time.Now(),
+				Source:  kgp.AUTOMATIC,
 			}
 		default:
 			var resign bool
@@ -110,6 +160,14 @@ func Play(g *kgp.Game, conf *conf.Conf) {
 			}
 			goto save
 		}
+		if conf.Validate {
+			err := g.Board.Validate(size, init)
+			if err != nil {
+				panic(fmt.Sprintf("Game %d: move %d by %s led to the invalid board %s: %s",
+					g.Id, m.Choice, side, g.Board, err))
+			}
+		}
+		m.State = g.Board.Copy()
 
 		// Save the move in the database, and take as much
 		// time as necessary.
@@ -130,11 +188,70 @@ func Play(g *kgp.Game, conf *conf.Conf) {
 save:
 	conf.DB.SaveGame(bg, g)
 	conf.Debug.Printf("Game %d finished (%s)", g.Id, &g.State)
+}
+
+// Placeholder for an agent that has not connected yet
+type Awaiting interface {
+	kgp.Agent
+
+	// Await blocks until an agent has connected, or the timeout
+	// has passed, in which case nil is returned.
+	Await(time.Duration) kgp.Agent
+}
+
+// Resume continues an interrupted game
+//
+// All Awaiting agents are given a grace period to connect.  If an
+// agent does not connect in time, the game is adjudicated based on
+// the board it was interrupted on (see kgp.Board.Adjudicate).
+func Resume(ctx context.Context, g *kgp.Game, conf *conf.Conf) {
+	var (
+		wait         sync.WaitGroup
+		north, south = g.North, g.South
+		done         = make(chan struct{})
+	)
 
-	if g.South != nil {
-		conf.GM.Schedule(g.South)
+	await := func(a *kgp.Agent) {
+		if w, ok := (*a).(Awaiting); ok {
+			wait.Add(1)
+			go func() {
+				*a = w.Await(conf.ResumeTimeout)
+				wait.Done()
+			}()
+		}
+	}
+	await(&north)
+	await(&south)
+	go func() {
+		wait.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		g.State = kgp.ABORTED
+		conf.DB.SaveGame(context.Background(), g)
+		conf.Debug.Printf("Game %d was interrupted while resuming", g.Id)
+		return
+	}
+
+	if north != nil && south != nil {
+		g.North, g.South = north, south
+		conf.Debug.Printf("Resuming game %d", g.Id)
+		Play(ctx, g, conf)
+		return
 	}
-	if g.North != nil {
-		conf.GM.Schedule(g.North)
+
+	switch g.Board.Adjudicate(kgp.South) {
+	case kgp.WIN:
+		g.State = kgp.SOUTH_WON
+	case kgp.LOSS:
+		g.State = kgp.NORTH_WON
+	case kgp.DRAW:
+		g.State = kgp.UNDECIDED
 	}
+	conf.DB.SaveGame(context.Background(), g)
+	conf.Debug.Printf("Game %d could not be resumed and was adjudicated (%s)",
+		g.Id, &g.State)
+	schedule(conf, north, south)
 }
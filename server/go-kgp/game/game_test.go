@@ -0,0 +1,174 @@
+// Game Model Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package game
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-kgp"
+	"go-kgp/conf"
+)
+
+// An agent that always makes the first legal move
+type first struct{ user kgp.User }
+
+func (a *first) Request(g *kgp.Game) (*kgp.Move, bool) {
+	size, _ := g.Board.Type()
+	for m := uint(0); m < size; m++ {
+		if g.Board.Legal(g.Current, m) {
+			return &kgp.Move{Choice: m, Agent: a, Game: g, Stamp: time.Now()}, false
+		}
+	}
+	return nil, true
+}
+
+func (a *first) User() *kgp.User { return &a.user }
+func (a *first) Alive() bool     { return true }
+
+// A placeholder that is taken over by an agent, unless it is nil
+type placeholder struct {
+	first
+	next kgp.Agent
+}
+
+func (p *placeholder) Await(time.Duration) kgp.Agent { return p.next }
+
+// A database that remembers the last state of every saved game
+type database struct {
+	conf.DatabaseManager
+	sync.Mutex
+	saved map[*kgp.Game]kgp.State
+}
+
+func (db *database) SaveGame(_ context.Context, g *kgp.Game) {
+	db.Lock()
+	db.saved[g] = g.State
+	db.Unlock()
+}
+
+func (db *database) SaveMove(context.Context, *kgp.Move) {}
+
+// A game manager that remembers what agents were scheduled
+type manager struct {
+	conf.GameManager
+	sync.Mutex
+	scheduled []kgp.Agent
+}
+
+func (gm *manager) Schedule(a kgp.Agent) {
+	gm.Lock()
+	gm.scheduled = append(gm.scheduled, a)
+	gm.Unlock()
+}
+
+func TestResume(t *testing.T) {
+	var (
+		north = &first{kgp.User{Token: "north"}}
+		south = &first{kgp.User{Token: "south"}}
+	)
+
+	// South has 2 + 4 stones, north 3 + 1 stones
+	const board = "<3, 2,3, 1,0,3, 1,0,0>"
+
+	for _, test := range []struct {
+		name         string
+		north, south kgp.Agent
+		board        string
+		state        kgp.State
+	}{
+		{"both", north, south, board, kgp.ONGOING},
+		{"south", nil, south, board, kgp.SOUTH_WON},
+		{"north", north, nil, board, kgp.SOUTH_WON},
+		{"neither", nil, nil, board, kgp.SOUTH_WON},
+		{"mirrored", north, nil, "<3, 3,2, 1,0,0, 1,0,3>", kgp.NORTH_WON},
+		{"draw", nil, south, "<3, 3,3, 3,3,3, 3,3,3>", kgp.UNDECIDED},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				db = &database{saved: make(map[*kgp.Game]kgp.State)}
+				gm = &manager{}
+				c  = conf.Conf{
+					Debug:         conf.Default(false).Debug,
+					DB:            db,
+					GM:            gm,
+					ResumeTimeout: time.Second,
+				}
+				b, err = kgp.Parse(test.board)
+				g      = &kgp.Game{
+					Id:      1,
+					Board:   b,
+					State:   kgp.ONGOING,
+					North:   &placeholder{next: test.north},
+					South:   &placeholder{next: test.south},
+					Current: kgp.North,
+				}
+			)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			Resume(context.Background(), g, &c)
+
+			state, ok := db.saved[g]
+			if !ok {
+				t.Fatal("The game was not saved")
+			}
+			if test.state == kgp.ONGOING {
+				// The resumed game has to be played
+				// until it is over
+				switch state {
+				case kgp.NORTH_WON, kgp.SOUTH_WON, kgp.UNDECIDED:
+				default:
+					t.Fatalf("Resumed game ended in state %s", &state)
+				}
+				if g.North != test.north || g.South != test.south {
+					t.Error("The placeholders were not replaced")
+				}
+			} else if state != test.state {
+				t.Fatalf("Expected state %s, got %s", &test.state, &state)
+			}
+
+			// Only agents that connected are scheduled again
+			var expect []kgp.Agent
+			for _, a := range []kgp.Agent{test.north, test.south} {
+				if a != nil {
+					expect = append(expect, a)
+				}
+			}
+			if len(gm.scheduled) != len(expect) {
+				t.Fatalf("Expected %d agents to be scheduled, got %d",
+					len(expect), len(gm.scheduled))
+			}
+			for _, a := range expect {
+				found := false
+				for _, s := range gm.scheduled {
+					found = found || s == a
+				}
+				if !found {
+					t.Errorf("%s was not scheduled", a.User().Token)
+				}
+			}
+		})
+	}
+}
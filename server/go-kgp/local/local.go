@@ -0,0 +1,149 @@
+// Local games without a server
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+// Package local allows games to be played without a database or a
+// network listener.
+//
+// Agents that speak KGP are connected over an in-memory connection
+// to the same protocol implementation the server uses, so that they
+// are subject to the same time control.  Games are played using
+// game.Play.
+package local
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"go-kgp"
+	"go-kgp/client"
+	"go-kgp/conf"
+	"go-kgp/proto"
+)
+
+// A database that does not store anything
+//
+// Moves are reported to a callback instead.
+type store struct {
+	move func(*kgp.Move)
+}
+
+func (*store) String() string { return "Local Store" }
+func (*store) Start()         {}
+func (*store) Shutdown()      {}
+
+func (*store) QueryUsers(context.Context, chan<- *kgp.User, int) {}
+func (*store) QueryUser(context.Context, int) *kgp.User          { return nil }
+func (*store) QueryUserToken(context.Context, string) *kgp.User  { return nil }
+func (*store) QueryGames(context.Context, int, chan<- *kgp.Game, int) {
+}
+func (*store) QueryGame(context.Context, int, chan<- *kgp.Game, chan<- *kgp.Move) {
+}
+func (*store) QueryOngoing(context.Context, chan<- *kgp.Game) {}
+func (*store) QueryLeaderboard(context.Context, kgp.Mancala, uint, uint, chan<- *kgp.Standing, int) {
+}
+func (*store) SaveGame(context.Context, *kgp.Game)        {}
+func (*store) DrawGraph(context.Context, io.Writer) error { return nil }
+
+func (s *store) SaveMove(_ context.Context, m *kgp.Move) {
+	if s.move != nil {
+		s.move(m)
+	}
+}
+
+// A game manager that reports when an agent is ready to play
+type queue struct {
+	ready chan kgp.Agent
+}
+
+func (*queue) String() string       { return "Local Queue" }
+func (*queue) Start()               {}
+func (*queue) Shutdown()            {}
+func (*queue) Unschedule(kgp.Agent) {}
+
+func (q *queue) Schedule(a kgp.Agent) {
+	// Agents are re-scheduled after every game, which can be
+	// ignored as the caller decides what games to play.
+	select {
+	case q.ready <- a:
+	default:
+	}
+}
+
+// Configure creates a configuration for local games
+//
+// TIMEOUT is the time an agent connected via KGP has for every move.
+// If MOVE is non-nil, it is invoked after every move.
+func Configure(timeout time.Duration, debug bool, move func(*kgp.Move)) *conf.Conf {
+	c := *conf.Default(debug)
+
+	c.MoveTimeout = timeout
+	c.Ping = false
+	c.ReconnectTimeout = 0
+	c.ResumeTimeout = 0
+	c.MaxConnIP = 0
+	c.MaxConnToken = 0
+	c.CommandRate = 0
+	c.DB = &store{move: move}
+	c.GM = &queue{}
+	c.Play = make(chan *kgp.Game, 1)
+
+	return &c
+}
+
+// Connect creates an in-memory connection to a KGP client handler
+//
+// The returned connection is the end the agent should use.  Wait
+// blocks until the agent has requested to play, and returns the agent
+// that can be used in a game.
+func Connect(c *conf.Conf) (conn net.Conn, wait func(context.Context) (kgp.Agent, error)) {
+	// Every connection has it's own queue, so that agents can be
+	// told apart.
+	cc := *c
+	q := &queue{ready: make(chan kgp.Agent, 1)}
+	cc.GM = q
+
+	conn, srv := net.Pipe()
+	proto.MakeClient(srv, &cc)
+
+	return conn, func(ctx context.Context) (kgp.Agent, error) {
+		select {
+		case a := <-q.ready:
+			return a, nil
+		case <-ctx.Done():
+			conn.Close()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Join connects an agent implemented using the client package
+//
+// The agent is connected until CTX is cancelled.
+func Join(ctx context.Context, c *conf.Conf, name string, agent client.Agent) (kgp.Agent, error) {
+	conn, wait := Connect(c)
+	go client.Run(ctx, &client.Config{
+		Name: name,
+		Dial: func(context.Context, string) (io.ReadWriteCloser, error) {
+			return conn, nil
+		},
+	}, agent)
+	return wait(ctx)
+}
@@ -0,0 +1,110 @@
+// Agents running as external processes
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package local
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"go-kgp"
+	"go-kgp/client"
+)
+
+// Process is an agent that starts a process for every request
+//
+// The process is given the board on the standard input and writes
+// moves onto the standard output, using the same protocol as kgpc
+// (see client/kgpc/README).  When the request is stopped, the process
+// is killed.
+type Process []string
+
+// WriteBoard writes BOARD to W as expected by a kgpc agent
+//
+// The board is written from the perspective of south, as the size,
+// both stores and then the pits of south and north, one per line.
+func WriteBoard(w io.Writer, board *kgp.Board) error {
+	size, _ := board.Type()
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "%d\n", size)
+	fmt.Fprintf(buf, "%d\n%d\n", board.Store(kgp.South), board.Store(kgp.North))
+	for i := uint(0); i < size; i++ {
+		fmt.Fprintf(buf, "%d\n", board.Pit(kgp.South, i))
+	}
+	for i := uint(0); i < size; i++ {
+		fmt.Fprintf(buf, "%d\n", board.Pit(kgp.North, i))
+	}
+	return buf.Flush()
+}
+
+func (p Process) String() string { return fmt.Sprint([]string(p)) }
+
+func (p Process) Search(ctx context.Context, board *kgp.Board, side kgp.Side) <-chan client.Move {
+	moves := make(chan client.Move)
+	go func() {
+		defer close(moves)
+
+		cmd := exec.CommandContext(ctx, p[0], p[1:]...)
+		cmd.Stderr = os.Stderr
+		in, err := cmd.StdinPipe()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if err = cmd.Start(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		defer cmd.Wait()
+
+		if side == kgp.North {
+			board = board.Mirror()
+		}
+		go func() {
+			WriteBoard(in, board)
+			in.Close()
+		}()
+
+		scanner := bufio.NewScanner(out)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			move, err := strconv.ParseUint(scanner.Text(), 10, 0)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot parse %q\n", scanner.Text())
+				continue
+			}
+			select {
+			case moves <- client.Move{Pit: uint(move)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return moves
+}
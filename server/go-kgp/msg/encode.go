@@ -0,0 +1,127 @@
+// Construction of KGP messages
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package msg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go-kgp"
+)
+
+// A string that is sent as a word, i.e. without quotes
+type Word string
+
+// Convert a value into an argument
+func makeArgument(arg interface{}) Argument {
+	switch v := arg.(type) {
+	case Argument:
+		return v
+	case string:
+		return Argument{Kind: STRING, Text: v}
+	case Word:
+		return Argument{Kind: WORD, Text: string(v)}
+	case int:
+		return Argument{Kind: INTEGER, Text: strconv.Itoa(v)}
+	case int64:
+		return Argument{Kind: INTEGER, Text: strconv.FormatInt(v, 10)}
+	case uint:
+		return Argument{Kind: INTEGER, Text: strconv.FormatUint(uint64(v), 10)}
+	case uint64:
+		return Argument{Kind: INTEGER, Text: strconv.FormatUint(v, 10)}
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			panic(fmt.Sprintf("Cannot encode %f", v))
+		}
+		text := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(text, ".") {
+			text += ".0"
+		}
+		return Argument{Kind: REAL, Text: text}
+	case *kgp.Board:
+		return Argument{Kind: BOARD, Text: v.String()}
+	}
+	panic(fmt.Sprintf("Unsupported type: %T", arg))
+}
+
+// Make creates a new command
+//
+// Each element in ARGS is converted into an argument depending on
+// its type: Strings are quoted, Words are not, integers and floats
+// are encoded as integers and reals and *kgp.Board as board literals.
+// Other types are not supported.  If ID or REF are 0, they are
+// omitted.
+func Make(id, ref uint64, name string, args ...interface{}) *Command {
+	cmd := &Command{Id: id, Ref: ref, Name: name}
+	for _, arg := range args {
+		cmd.Args = append(cmd.Args, makeArgument(arg))
+	}
+	return cmd
+}
+
+// Protocol version
+type Version struct {
+	Major, Minor, Patch uint64
+}
+
+// The protocol version implemented by this package
+var Current = Version{Major: 1, Minor: 0, Patch: 1}
+
+// Errors during version negotiation
+var (
+	ErrNoVersion    = errors.New("Expected a \"kgp\" command")
+	ErrIncompatible = errors.New("Unsupported protocol version")
+)
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Greeting returns the "kgp" command announcing version V
+func (v Version) Greeting(id uint64) *Command {
+	return Make(id, 0, "kgp", v.Major, v.Minor, v.Patch)
+}
+
+// Compatible checks if V can be used with the Current version
+func (v Version) Compatible() bool {
+	return v.Major == Current.Major
+}
+
+// ParseVersion extracts the version from a "kgp" command
+//
+// If the command is not a valid greeting, ErrNoVersion or an
+// ArgumentError is returned.  If the version is not compatible with
+// the Current version, ErrIncompatible is returned.
+func ParseVersion(cmd *Command) (v Version, err error) {
+	if cmd.Name != "kgp" {
+		return v, ErrNoVersion
+	}
+	err = cmd.Scan(&v.Major, &v.Minor, &v.Patch)
+	if err != nil {
+		return v, err
+	}
+	if !v.Compatible() {
+		return v, ErrIncompatible
+	}
+	return v, nil
+}
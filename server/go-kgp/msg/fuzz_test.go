@@ -0,0 +1,51 @@
+// Message Fuzz Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+//go:build go1.18
+// +build go1.18
+
+package msg
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Check that every command that can be lexed can be converted back
+// into a string and lexed again.  The corpus is located in
+// testdata/fuzz/FuzzLex, and can be extended using
+//
+//	go test -fuzz FuzzLex ./msg
+func FuzzLex(f *testing.F) {
+	f.Fuzz(func(t *testing.T, input string) {
+		cmd, err := Lex(input)
+		if err != nil {
+			return
+		}
+
+		again, err := Lex(cmd.String())
+		if err != nil {
+			t.Fatalf("Failed to lex %q (from %q): %s", cmd, input, err)
+		}
+		if !reflect.DeepEqual(cmd, again) {
+			t.Fatalf("Lexing %q resulted in %#v, expected %#v",
+				cmd, again, cmd)
+		}
+	})
+}
@@ -0,0 +1,230 @@
+// Grammar Round-Trip Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package msg
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-kgp"
+)
+
+// Generators for the productions of the grammar in spec/012-grammar.md
+
+const (
+	digits  = "0123456789"
+	alpha   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	wsp     = " \t"
+	special = "\"\\\r\n !#$%&'()*+,-./:;<=>?@[]^_`{|}~"
+)
+
+func pick(r *rand.Rand, set string, min, max int) string {
+	var buf strings.Builder
+	n := min + r.Intn(max-min+1)
+	for i := 0; i < n; i++ {
+		buf.WriteByte(set[r.Intn(len(set))])
+	}
+	return buf.String()
+}
+
+func sign(r *rand.Rand) string {
+	return []string{"", "+", "-"}[r.Intn(3)]
+}
+
+// Generate an argument, returning the textual representation and
+// the expected result of lexing it
+func genArgument(r *rand.Rand) (string, Argument) {
+	switch Kind(r.Intn(5)) {
+	case INTEGER:
+		text := sign(r) + pick(r, digits, 1, 6)
+		return text, Argument{INTEGER, text}
+	case REAL:
+		text := sign(r) + pick(r, digits, 0, 4) + "." + pick(r, digits, 1, 4)
+		return text, Argument{REAL, text}
+	case WORD:
+		// A word must not be confused with an integer
+		text := pick(r, alpha, 1, 1) + pick(r, alpha+digits+"-:", 0, 8)
+		if r.Intn(2) == 0 {
+			text = pick(r, digits, 0, 2) + text
+		}
+		return text, Argument{WORD, text}
+	case STRING:
+		var raw, val strings.Builder
+		raw.WriteByte('"')
+		for i, n := 0, r.Intn(12); i < n; i++ {
+			c := pick(r, alpha+digits+wsp+special, 1, 1)
+			switch c {
+			case "\"", "\\", "\r":
+				raw.WriteString(`\` + c)
+				val.WriteString(c)
+			case "\n":
+				raw.WriteString(`\n`)
+				val.WriteString(c)
+			default:
+				if c != "n" && r.Intn(8) == 0 {
+					// Unnecessary escape sequence
+					raw.WriteString(`\`)
+				}
+				raw.WriteString(c)
+				val.WriteString(c)
+			}
+		}
+		raw.WriteByte('"')
+		return raw.String(), Argument{STRING, val.String()}
+	case BOARD:
+		var buf strings.Builder
+		buf.WriteString("<" + pick(r, digits, 1, 3))
+		for i, n := 0, r.Intn(10); i < n; i++ {
+			buf.WriteString("," + pick(r, digits, 1, 3))
+		}
+		buf.WriteString(">")
+		return buf.String(), Argument{BOARD, buf.String()}
+	}
+	panic("Unreachable")
+}
+
+// Generate a command, returning the textual representation and the
+// expected result of lexing it
+func genCommand(r *rand.Rand) (string, *Command) {
+	var (
+		buf strings.Builder
+		cmd Command
+	)
+
+	buf.WriteString(pick(r, wsp, 0, 2))
+	if r.Intn(2) == 0 {
+		cmd.Id = uint64(r.Intn(1000) + 1)
+		fmt.Fprint(&buf, cmd.Id)
+	}
+	if r.Intn(3) == 0 {
+		cmd.Ref = uint64(r.Intn(1000) + 1)
+		fmt.Fprintf(&buf, "@%d", cmd.Ref)
+	}
+	if cmd.Id != 0 || cmd.Ref != 0 {
+		buf.WriteString(pick(r, wsp, 1, 3))
+	}
+
+	cmd.Name = pick(r, alpha, 1, 1) + pick(r, alpha+digits, 0, 6)
+	buf.WriteString(cmd.Name)
+
+	for i, n := 0, r.Intn(5); i < n; i++ {
+		text, arg := genArgument(r)
+		buf.WriteString(pick(r, wsp, 1, 3))
+		buf.WriteString(text)
+		cmd.Args = append(cmd.Args, arg)
+	}
+	buf.WriteString(pick(r, wsp, 0, 2))
+
+	return buf.String(), &cmd
+}
+
+func TestGrammar(t *testing.T) {
+	r := rand.New(rand.NewSource(2671))
+
+	for i := 0; i < 10000; i++ {
+		input, expected := genCommand(r)
+
+		cmd, err := Lex(input)
+		if err != nil {
+			t.Fatalf("Failed to lex %q: %s", input, err)
+		}
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Fatalf("Lexed %q as %#v, expected %#v", input, cmd, expected)
+		}
+
+		again, err := Lex(cmd.String())
+		if err != nil {
+			t.Fatalf("Failed to lex %q (from %q): %s", cmd, input, err)
+		}
+		if !reflect.DeepEqual(cmd, again) {
+			t.Fatalf("Encoding %q resulted in %q", input, cmd)
+		}
+	}
+}
+
+func TestMake(t *testing.T) {
+	board := kgp.MakeBoard(3, 2)
+	cmd := Make(3, 1, "test",
+		"a \"string\"\n", Word("word"), -1, int64(-2), uint(3), uint64(4),
+		0.5, 2.0, board)
+
+	parsed, err := Lex(cmd.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmd, parsed) {
+		t.Fatalf("Encoding %#v resulted in %#v", cmd, parsed)
+	}
+
+	var (
+		s, w   string
+		i, j   int64
+		u, v   uint64
+		f, g   float64
+		b      *kgp.Board
+		expect = []interface{}{
+			"a \"string\"\n", "word", int64(-1), int64(-2),
+			uint64(3), uint64(4), 0.5, 2.0, board.String(),
+		}
+	)
+	err = parsed.Scan(&s, &w, &i, &j, &u, &v, &f, &g, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []interface{}{s, w, i, j, u, v, f, g, b.String()}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("Scanned %v, expected %v", got, expect)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	v, err := ParseVersion(Current.Greeting(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != Current {
+		t.Fatalf("Parsed %s, expected %s", v, Current)
+	}
+
+	for input, expected := range map[string]error{
+		"kgp 1 2 3":   nil,
+		"kgp 2 0 0":   ErrIncompatible,
+		"kgp 0 9 0":   ErrIncompatible,
+		"ping":        ErrNoVersion,
+		"kgp 1 0":     ErrArgumentMismatch,
+		"kgp 1 0 0 0": ErrArgumentMismatch,
+	} {
+		cmd, err := Lex(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ParseVersion(cmd); err != expected {
+			t.Errorf("Parsing %q returned %v, expected %v", input, err, expected)
+		}
+	}
+
+	cmd, _ := Lex(`kgp "1" 0 0`)
+	if _, err := ParseVersion(cmd); err == nil {
+		t.Error("Expected a non-integer version to be rejected")
+	}
+}
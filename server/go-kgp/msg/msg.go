@@ -0,0 +1,490 @@
+// Encoding and decoding of KGP messages
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+// Package msg implements the syntax of the Kalah Game Protocol
+//
+// It is used by the server and the clients alike, to lex and encode
+// commands, board literals and to negotiate the protocol version.
+package msg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-kgp"
+)
+
+// Maximal length of a command, excluding the line terminator
+const MAX_LENGTH = 16384
+
+// Type of an argument
+type Kind uint8
+
+const (
+	INTEGER Kind = iota
+	REAL
+	WORD
+	STRING
+	BOARD
+)
+
+func (k Kind) String() string {
+	switch k {
+	case INTEGER:
+		return "integer"
+	case REAL:
+		return "real"
+	case WORD:
+		return "word"
+	case STRING:
+		return "string"
+	case BOARD:
+		return "board"
+	}
+	panic("Illegal kind")
+}
+
+// An argument of a command
+type Argument struct {
+	Kind Kind
+	// The textual representation of the argument.  Strings are
+	// stored without quotes and escape sequences.
+	Text string
+}
+
+// A command, consisting of an optional ID and reference, a name and
+// any number of arguments
+type Command struct {
+	Id, Ref uint64
+	Name    string
+	Args    []Argument
+}
+
+// Error in the syntax of a command
+type SyntaxError struct {
+	Offset int // Offset of the error in the input
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax error at position %d: %s", e.Offset+1, e.Reason)
+}
+
+// Error when assigning the arguments of a command
+type ArgumentError struct {
+	Index  int // Index of the argument, starting with 0
+	Reason string
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("Invalid argument %d: %s", e.Index+1, e.Reason)
+}
+
+var (
+	// Error to return if a command is too long
+	ErrTooLong = fmt.Errorf("Command exceeds %d characters", MAX_LENGTH)
+
+	// Error to return if the number of arguments is wrong
+	ErrArgumentMismatch = errors.New("Argument mismatch")
+)
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+func isAlpha(c byte) bool { return 'A' <= c&^0x20 && c&^0x20 <= 'Z' }
+func isAlnum(c byte) bool { return isDigit(c) || isAlpha(c) }
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f' }
+
+// A lexer keeps track of the position in the input
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) eof() bool  { return l.pos >= len(l.input) }
+func (l *lexer) peek() byte { return l.input[l.pos] }
+
+func (l *lexer) fail(format string, args ...interface{}) error {
+	return &SyntaxError{
+		Offset: l.pos,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+// Skip over white space and report if any was found
+func (l *lexer) space() bool {
+	start := l.pos
+	for !l.eof() && isSpace(l.peek()) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+// Consume a sequence of characters matching P
+func (l *lexer) span(p func(byte) bool) string {
+	start := l.pos
+	for !l.eof() && p(l.peek()) {
+		l.pos++
+	}
+	return l.input[start:l.pos]
+}
+
+// Lex a number consisting of digits
+func (l *lexer) number() (uint64, error) {
+	start := l.pos
+	digits := l.span(isDigit)
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		l.pos = start
+		return 0, l.fail("invalid number %q", digits)
+	}
+	return n, nil
+}
+
+// Lex a string, starting with a double quote
+func (l *lexer) string() (string, error) {
+	var buf strings.Builder
+
+	l.pos++ // skip opening quote
+	for !l.eof() {
+		switch c := l.peek(); c {
+		case '"':
+			l.pos++
+			return buf.String(), nil
+		case '\\':
+			l.pos++
+			if l.eof() {
+				return "", l.fail("incomplete escape sequence")
+			}
+			if l.peek() == 'n' {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteByte(l.peek())
+			}
+		case '\n':
+			return "", l.fail("line break in string")
+		default:
+			buf.WriteByte(c)
+		}
+		l.pos++
+	}
+	return "", l.fail("unterminated string")
+}
+
+// Lex a board literal, starting with an opening angle bracket
+func (l *lexer) board() (string, error) {
+	start := l.pos
+
+	l.pos++ // skip opening bracket
+	for {
+		if l.eof() || !isDigit(l.peek()) {
+			return "", l.fail("expected a digit in board literal")
+		}
+		l.span(isDigit)
+		if l.eof() {
+			return "", l.fail("unterminated board literal")
+		}
+		switch l.peek() {
+		case ',':
+			l.pos++
+		case '>':
+			l.pos++
+			return l.input[start:l.pos], nil
+		default:
+			return "", l.fail("unexpected %q in board literal", l.peek())
+		}
+	}
+}
+
+// Lex an integer, real or a word
+func (l *lexer) atom() (Argument, error) {
+	start := l.pos
+	text := l.span(func(c byte) bool {
+		return isAlnum(c) || c == '-' || c == '+' || c == ':' || c == '.'
+	})
+	if text == "" {
+		return Argument{}, l.fail("unexpected %q", l.peek())
+	}
+
+	// Determine the type of the argument
+	var (
+		i      int
+		before int
+		point  bool
+		after  int
+	)
+	if text[i] == '+' || text[i] == '-' {
+		i++
+	}
+	for ; i < len(text) && isDigit(text[i]); i++ {
+		before++
+	}
+	if i < len(text) && text[i] == '.' {
+		point = true
+		for i++; i < len(text) && isDigit(text[i]); i++ {
+			after++
+		}
+	}
+	switch {
+	case i == len(text) && !point && before > 0:
+		return Argument{Kind: INTEGER, Text: text}, nil
+	case i == len(text) && point && after > 0:
+		return Argument{Kind: REAL, Text: text}, nil
+	}
+
+	for j := 0; j < len(text); j++ {
+		if c := text[j]; !isAlnum(c) && c != '-' && c != ':' {
+			l.pos = start + j
+			return Argument{}, l.fail("unexpected %q", c)
+		}
+	}
+	return Argument{Kind: WORD, Text: text}, nil
+}
+
+// Lex parses a single command
+//
+// If the command could be partially lexed before an error occurred,
+// the returned command contains the ID and reference of the command,
+// so that the error can be reported to the sender.
+func Lex(input string) (*Command, error) {
+	var (
+		l   = lexer{input: input}
+		cmd = &Command{}
+		err error
+	)
+
+	l.space()
+
+	// Parse the ID and the reference of the command
+	start := l.pos
+	if !l.eof() && isDigit(l.peek()) {
+		cmd.Id, err = l.number()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !l.eof() && l.peek() == '@' {
+		l.pos++
+		if l.eof() || !isDigit(l.peek()) {
+			return cmd, l.fail("expected a reference")
+		}
+		cmd.Ref, err = l.number()
+		if err != nil {
+			return cmd, err
+		}
+	}
+	if l.pos > start && !l.space() {
+		// A command name may consist of digits as well, in
+		// which case the ID has to be reinterpreted.
+		if l.eof() || !isAlpha(l.peek()) || cmd.Ref != 0 {
+			return cmd, l.fail("expected white space after the command ID")
+		}
+		l.pos = start
+		cmd.Id = 0
+	}
+
+	if len(input) > MAX_LENGTH {
+		return cmd, ErrTooLong
+	}
+
+	// Parse the name of the command
+	cmd.Name = l.span(isAlnum)
+	if cmd.Name == "" {
+		if l.eof() {
+			return cmd, l.fail("missing command name")
+		}
+		return cmd, l.fail("unexpected %q in command name", l.peek())
+	}
+	if strings.IndexFunc(cmd.Name, func(r rune) bool {
+		return !isDigit(byte(r))
+	}) == -1 {
+		// Names consisting only of digits cannot be
+		// distinguished from IDs.
+		return cmd, l.fail("command name must contain a letter")
+	}
+
+	// Parse the arguments
+	for {
+		sep := l.space()
+		if l.eof() {
+			break
+		}
+		if !sep {
+			return cmd, l.fail("expected white space")
+		}
+
+		var arg Argument
+		switch l.peek() {
+		case '"':
+			arg.Kind = STRING
+			arg.Text, err = l.string()
+		case '<':
+			arg.Kind = BOARD
+			arg.Text, err = l.board()
+		default:
+			arg, err = l.atom()
+		}
+		if err != nil {
+			return cmd, err
+		}
+		cmd.Args = append(cmd.Args, arg)
+	}
+
+	return cmd, nil
+}
+
+// Scan assigns the arguments of a command to PARAMS
+//
+// Each parameter must be a pointer to a value of type string,
+// uint64, int64, float64 or *kgp.Board.  Every argument can be
+// assigned to a string, in which case the textual representation of
+// the argument is used.
+func (cmd *Command) Scan(params ...interface{}) error {
+	if len(params) != len(cmd.Args) {
+		return ErrArgumentMismatch
+	}
+
+	for i, param := range params {
+		var (
+			arg = cmd.Args[i]
+			err error
+		)
+
+		mismatch := func(expected string) error {
+			return &ArgumentError{
+				Index:  i,
+				Reason: fmt.Sprintf("expected %s, got %s", expected, arg.Kind),
+			}
+		}
+
+		switch p := param.(type) {
+		case *string:
+			*p = arg.Text
+		case *uint64:
+			if arg.Kind != INTEGER {
+				return mismatch("an integer")
+			}
+			*p, err = strconv.ParseUint(strings.TrimPrefix(arg.Text, "+"), 10, 64)
+		case *int64:
+			if arg.Kind != INTEGER {
+				return mismatch("an integer")
+			}
+			*p, err = strconv.ParseInt(arg.Text, 10, 64)
+		case *float64:
+			if arg.Kind != INTEGER && arg.Kind != REAL {
+				return mismatch("a number")
+			}
+			*p, err = strconv.ParseFloat(arg.Text, 64)
+		case **kgp.Board:
+			if arg.Kind != BOARD {
+				return mismatch("a board")
+			}
+			*p, err = kgp.Parse(arg.Text)
+		default:
+			panic(fmt.Sprintf("Unsupported type: %T", param))
+		}
+		if err != nil {
+			return &ArgumentError{Index: i, Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// Quote a string so that it can be sent as an argument
+func Quote(s string) string {
+	var buf strings.Builder
+
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', '\r':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+
+	return buf.String()
+}
+
+// String converts a command into its textual representation
+func (cmd *Command) String() string {
+	var buf strings.Builder
+
+	if cmd.Id > 0 {
+		fmt.Fprint(&buf, cmd.Id)
+	}
+	if cmd.Ref > 0 {
+		fmt.Fprintf(&buf, "@%d", cmd.Ref)
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(cmd.Name)
+	for _, arg := range cmd.Args {
+		buf.WriteByte(' ')
+		if arg.Kind == STRING {
+			buf.WriteString(Quote(arg.Text))
+		} else {
+			buf.WriteString(arg.Text)
+		}
+	}
+
+	return buf.String()
+}
+
+// SplitCommands splits input into lines, ignoring overlong lines
+//
+// A line that exceeds MAX_LENGTH is truncated to one character more
+// than the limit, so that Lex can report the error to the sender.
+// The remainder of the line is discarded.
+func SplitCommands() bufio.SplitFunc {
+	var discard bool
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			if discard {
+				discard = false
+				return i + 1, nil, nil
+			}
+			return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+		}
+		if len(data) > MAX_LENGTH+1 {
+			if discard {
+				return len(data), nil, nil
+			}
+			discard = true
+			return len(data), data[:MAX_LENGTH+1], nil
+		}
+		if atEOF && len(data) > 0 {
+			if discard {
+				return len(data), nil, nil
+			}
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
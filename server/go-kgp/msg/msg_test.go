@@ -0,0 +1,252 @@
+// Message Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package msg
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-kgp"
+)
+
+func TestLex(t *testing.T) {
+	for i, test := range []struct {
+		input string
+		cmd   Command
+	}{
+		{
+			input: "ping",
+			cmd:   Command{Name: "ping"},
+		},
+		{
+			input: "  12@7  move   3  ",
+			cmd: Command{
+				Id: 12, Ref: 7, Name: "move",
+				Args: []Argument{{INTEGER, "3"}},
+			},
+		},
+		{
+			input: "@7 yield",
+			cmd:   Command{Ref: 7, Name: "yield"},
+		},
+		{
+			input: "kgp 1 0 1",
+			cmd: Command{
+				Name: "kgp",
+				Args: []Argument{{INTEGER, "1"}, {INTEGER, "0"}, {INTEGER, "1"}},
+			},
+		},
+		{
+			input: "set info:name \"a \\\"quoted\\\" \\\\ name\\n\"",
+			cmd: Command{
+				Name: "set",
+				Args: []Argument{{WORD, "info:name"}, {STRING, "a \"quoted\" \\ name\n"}},
+			},
+		},
+		{
+			input: "x +1 -1 -.5 +3.25 0.0 - a-b 1a",
+			cmd: Command{
+				Name: "x",
+				Args: []Argument{
+					{INTEGER, "+1"}, {INTEGER, "-1"},
+					{REAL, "-.5"}, {REAL, "+3.25"}, {REAL, "0.0"},
+					{WORD, "-"}, {WORD, "a-b"}, {WORD, "1a"},
+				},
+			},
+		},
+		{
+			input: "4 state <3,10,2,1,2,3,4,2,0>",
+			cmd: Command{
+				Id: 4, Name: "state",
+				Args: []Argument{{BOARD, "<3,10,2,1,2,3,4,2,0>"}},
+			},
+		},
+		{
+			input: "12move",
+			cmd:   Command{Name: "12move"},
+		},
+		{
+			input: "set x \"\"",
+			cmd: Command{
+				Name: "set",
+				Args: []Argument{{WORD, "x"}, {STRING, ""}},
+			},
+		},
+	} {
+		cmd, err := Lex(test.input)
+		if err != nil {
+			t.Errorf("(%d) Failed to lex %q: %s", i, test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(*cmd, test.cmd) {
+			t.Errorf("(%d) Lexed %q as %#v, expected %#v",
+				i, test.input, *cmd, test.cmd)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	for i, test := range []struct {
+		input string
+		id    uint64 // ID that should be reported
+	}{
+		{input: "1", id: 1},
+		{input: "12 34", id: 12},
+		{input: "1@ move", id: 1},
+		{input: "1@2move", id: 1},
+		{input: "move-1"},
+		{input: "3 set x \"unterminated", id: 3},
+		{input: "3 set x \"escape\\", id: 3},
+		{input: "3 set x \"a\"b", id: 3},
+		{input: "3 state <>", id: 3},
+		{input: "3 state <1,2", id: 3},
+		{input: "3 state <1,,2>", id: 3},
+		{input: "3 state <1 ,2>", id: 3},
+		{input: "3 x 1.", id: 3},
+		{input: "3 x 1.a", id: 3},
+		{input: "3 x a!", id: 3},
+		{input: "3 x +", id: 3},
+		{input: "99999999999999999999 x"},
+		{input: "3 x " + strings.Repeat("a", MAX_LENGTH), id: 3},
+	} {
+		cmd, err := Lex(test.input)
+		if err == nil {
+			t.Errorf("(%d) Expected lexing %q to fail", i, test.input)
+			continue
+		}
+		if cmd != nil && cmd.Id != test.id {
+			t.Errorf("(%d) Reported ID %d, expected %d", i, cmd.Id, test.id)
+		}
+	}
+
+	_, err := Lex(strings.Repeat(" ", MAX_LENGTH) + "x")
+	if err != ErrTooLong {
+		t.Errorf("Expected %q, got %v", ErrTooLong, err)
+	}
+	_, err = Lex(strings.Repeat(" ", MAX_LENGTH-1) + "x")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	cmd, err := Lex(`x 1 -2 +.5 "str" word <1,0,0,1,1>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		u uint64
+		i int64
+		f float64
+		s string
+		w string
+		b *kgp.Board
+	)
+	err = cmd.Scan(&u, &i, &f, &s, &w, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != 1 || i != -2 || f != .5 || s != "str" || w != "word" ||
+		b.String() != "<1,0,0,1,1>" {
+		t.Fatalf("Wrong values: %d %d %f %q %q %s", u, i, f, s, w, b)
+	}
+
+	// Strings accept any argument
+	err = cmd.Scan(&s, &s, &s, &s, &s, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "<1,0,0,1,1>" {
+		t.Fatalf("Unexpected string %q", s)
+	}
+
+	for _, params := range [][]interface{}{
+		{&u},
+		{&u, &u, &u, &u, &u, &u},
+		{&u, &u, &f, &s, &s, &b},
+		{&u, &i, &f, &b, &s, &b},
+		{&u, &i, &f, &s, &s, &s, &s},
+	} {
+		if cmd.Scan(params...) == nil {
+			t.Errorf("Expected scanning into %T to fail", params)
+		}
+	}
+
+	// Invalid board literals are rejected when scanned
+	cmd, err = Lex(`x <2,0,0,1>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var aerr *ArgumentError
+	if err = cmd.Scan(&b); !errors.As(err, &aerr) {
+		t.Fatalf("Expected an argument error, got %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, str := range []string{
+		"", "plain", `"`, `\`, "\n", "\r", `\n`, "a \"b\" \\c\\ \n",
+	} {
+		cmd, err := Lex("x " + Quote(str))
+		if err != nil {
+			t.Errorf("Failed to lex %q: %s", Quote(str), err)
+			continue
+		}
+		var res string
+		if err := cmd.Scan(&res); err != nil {
+			t.Error(err)
+		} else if res != str {
+			t.Errorf("Quoting %q returned %q", str, res)
+		}
+	}
+}
+
+func TestSplitCommands(t *testing.T) {
+	long := strings.Repeat("a", 2*MAX_LENGTH)
+	input := "first\r\n" + long + "\nsecond\n" + long + "\nthird"
+
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(SplitCommands())
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines, got %d", len(lines))
+	}
+	for i, expected := range []string{"first", "", "second", "", "third"} {
+		if expected == "" {
+			if len(lines[i]) != MAX_LENGTH+1 {
+				t.Errorf("Line %d was not truncated (%d)", i, len(lines[i]))
+			}
+		} else if lines[i] != expected {
+			t.Errorf("Line %d should be %q, got %q", i, expected, lines[i])
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Oware Board Implementation
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp . If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import "fmt"
+
+// Mancala designates a game of the Mancala family
+type Mancala uint8
+
+const (
+	KALAH Mancala = iota
+	OWARE
+)
+
+const (
+	// Number of pits on each side of an Oware board
+	OWARE_SIZE = 6
+	// Number of seeds in every pit at the beginning of a game
+	OWARE_INIT = 4
+	// Number of moves without a capture, after which a game of
+	// Oware is considered to be in a cycle and ends
+	OWARE_IDLE = 100
+)
+
+func (m Mancala) String() string {
+	switch m {
+	case KALAH:
+		return "kalah"
+	case OWARE:
+		return "oware"
+	default:
+		panic(fmt.Sprintf("Illegal game: %d", m))
+	}
+}
+
+// ParseMancala returns the game designated by NAME
+func ParseMancala(name string) (Mancala, error) {
+	switch name {
+	case "kalah":
+		return KALAH, nil
+	case "oware":
+		return OWARE, nil
+	default:
+		return KALAH, fmt.Errorf("Unknown game %q", name)
+	}
+}
+
+// Scan implements the sql.Scanner interface
+//
+// A missing value designates a game of Kalah.
+func (m *Mancala) Scan(src interface{}) (err error) {
+	switch v := src.(type) {
+	case nil:
+		*m = KALAH
+	case string:
+		*m, err = ParseMancala(v)
+	case []byte:
+		*m, err = ParseMancala(string(v))
+	default:
+		err = fmt.Errorf("Invalid type %T", src)
+	}
+	return
+}
+
+// Create a new Oware board
+//
+// The stores of an Oware board are not sown into, but hold the seeds
+// that have been captured by each player.
+func MakeOware() *Board {
+	b := MakeBoard(OWARE_SIZE, OWARE_INIT)
+	b.mancala = OWARE
+	return b
+}
+
+// Return the pits of SIDE
+func (b *Board) pits(side Side) []uint {
+	if side == North {
+		return b.northPits
+	}
+	return b.southPits
+}
+
+// Check if the pits of SIDE are all empty
+func empty(pits []uint) bool {
+	for _, p := range pits {
+		if p > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if sowing PIT on a side reaches the opponent
+func feeds(pits []uint, pit uint) bool {
+	return pits[pit] >= uint(len(pits))-pit
+}
+
+// In Oware a player must leave the opponent seeds to play with, if
+// possible.
+func (b *Board) legalOware(side Side, pit uint) bool {
+	own := b.pits(side)
+	if own[pit] == 0 {
+		return false
+	}
+	return !empty(b.pits(!side)) || feeds(own, pit)
+}
+
+// The game is over, if either player has captured more than half of
+// the seeds, if one side cannot be fed by the other side or if the
+// players are caught in a cycle.
+func (b *Board) overOware() bool {
+	var seeds uint
+	for _, side := range []Side{North, South} {
+		for _, p := range b.pits(side) {
+			seeds += p
+		}
+	}
+	seeds += b.north + b.south
+	if b.north > seeds/2 || b.south > seeds/2 {
+		return true
+	}
+	if b.idle >= OWARE_IDLE {
+		return true
+	}
+
+	for _, side := range []Side{North, South} {
+		if !empty(b.pits(side)) {
+			continue
+		}
+		other := b.pits(!side)
+		fed := false
+		for i := range other {
+			fed = fed || feeds(other, uint(i))
+		}
+		if !fed {
+			return true
+		}
+	}
+	return false
+}
+
+// Sow PIT for player SELF on an Oware board
+//
+// The origin pit is skipped when sowing twelve seeds or more.  If the
+// last seed lands in a pit of the opponent and makes it contain two or
+// three seeds, these are captured, as are all the preceding pits of
+// the opponent with two or three seeds.  A capture that would take all
+// seeds of the opponent (a "grand slam") is forfeited.  There are no
+// repeat-moves in Oware.
+func (b *Board) sowOware(self Side, pit uint) bool {
+	if !b.Legal(self, pit) {
+		panic(fmt.Sprintf("Illegal move %d by %s in %s",
+			pit, self, b))
+	}
+
+	var (
+		size   = uint(len(b.northPits))
+		own    = b.pits(self)
+		stones = own[pit]
+		side   = self
+		pos    = pit
+	)
+
+	own[pit] = 0
+	for stones > 0 {
+		pos++
+		if pos == size {
+			side = !side
+			pos = 0
+		}
+		if side == self && pos == pit {
+			continue
+		}
+		b.pits(side)[pos]++
+		stones--
+	}
+
+	b.idle++
+	if side != self {
+		var (
+			other = b.pits(side)
+			seeds uint
+			taken uint
+			first = int(pos)
+		)
+		for _, p := range other {
+			seeds += p
+		}
+		for first >= 0 && (other[first] == 2 || other[first] == 3) {
+			taken += other[first]
+			first--
+		}
+		if taken > 0 && taken < seeds {
+			for i := first + 1; i <= int(pos); i++ {
+				other[i] = 0
+			}
+			if self == North {
+				b.north += taken
+			} else {
+				b.south += taken
+			}
+			b.idle = 0
+		}
+	}
+
+	if b.Over() {
+		b.Collect()
+	}
+
+	return false
+}
@@ -0,0 +1,211 @@
+// Oware Board Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp . If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestOwareSow(t *testing.T) {
+	for i, test := range []struct {
+		start, end *Board
+		move       uint
+	}{
+		{ // no stores are sown into
+			start: MakeOware(),
+			end: &Board{
+				northPits: []uint{4, 4, 4, 4, 4, 4},
+				southPits: []uint{0, 5, 5, 5, 5, 4},
+				idle:      1,
+			},
+			move: 0,
+		}, {
+			start: MakeOware(),
+			end: &Board{
+				northPits: []uint{5, 5, 5, 5, 4, 4},
+				southPits: []uint{4, 4, 4, 4, 4, 0},
+				idle:      1,
+			},
+			move: 5,
+		}, { // capture a chain of pits with two or three seeds
+			start: &Board{
+				northPits: []uint{1, 2, 1, 5, 1, 1},
+				southPits: []uint{0, 0, 0, 0, 0, 3},
+			},
+			end: &Board{
+				south:     7,
+				northPits: []uint{0, 0, 0, 5, 1, 1},
+				southPits: []uint{0, 0, 0, 0, 0, 0},
+			},
+			move: 5,
+		}, { // the chain is interrupted
+			start: &Board{
+				northPits: []uint{1, 5, 1, 5, 1, 1},
+				southPits: []uint{0, 0, 0, 0, 1, 3},
+			},
+			end: &Board{
+				south:     2,
+				northPits: []uint{2, 6, 0, 5, 1, 1},
+				southPits: []uint{0, 0, 0, 0, 1, 0},
+			},
+			move: 5,
+		}, { // a grand slam does not capture
+			start: &Board{
+				northPits: []uint{1, 2, 0, 0, 0, 0},
+				southPits: []uint{0, 0, 0, 0, 1, 2},
+			},
+			end: &Board{
+				northPits: []uint{2, 3, 0, 0, 0, 0},
+				southPits: []uint{0, 0, 0, 0, 1, 0},
+				idle:      1,
+			},
+			move: 5,
+		}, { // the origin is skipped
+			start: &Board{
+				northPits: []uint{0, 0, 0, 0, 0, 0},
+				southPits: []uint{12, 0, 0, 0, 0, 0},
+			},
+			end: &Board{
+				northPits: []uint{1, 1, 1, 1, 1, 1},
+				southPits: []uint{0, 2, 1, 1, 1, 1},
+				idle:      1,
+			},
+			move: 0,
+		}, { // north cannot feed south and collects all seeds
+			start: &Board{
+				northPits: []uint{1, 2, 0, 0, 0, 0},
+				southPits: []uint{0, 0, 0, 0, 0, 2},
+			},
+			end: &Board{
+				north:     5,
+				northPits: []uint{0, 0, 0, 0, 0, 0},
+				southPits: []uint{0, 0, 0, 0, 0, 0},
+				idle:      1,
+			},
+			move: 5,
+		}, { // a cycle ends the game
+			start: &Board{
+				north:     20,
+				south:     20,
+				northPits: []uint{0, 0, 0, 3, 0, 1},
+				southPits: []uint{0, 0, 0, 2, 1, 1},
+				idle:      OWARE_IDLE - 1,
+			},
+			end: &Board{
+				north:     24,
+				south:     24,
+				northPits: []uint{0, 0, 0, 0, 0, 0},
+				southPits: []uint{0, 0, 0, 0, 0, 0},
+				idle:      OWARE_IDLE,
+			},
+			move: 4,
+		},
+	} {
+		test.start.SetMancala(OWARE)
+		test.end.SetMancala(OWARE)
+		test.end.init = test.start.init
+		mirror := test.start.Mirror().Copy()
+
+		if test.start.Sow(South, test.move) {
+			t.Errorf("(%d) Unexpected repeat move", i)
+		} else if !reflect.DeepEqual(test.start, test.end) {
+			t.Errorf("(%d) Expected %s, got %s", i, test.end, test.start)
+		}
+
+		mirror.Sow(North, test.move)
+		if !reflect.DeepEqual(mirror, test.end.Mirror()) {
+			t.Errorf("(%d) Expected %s, got %s for north",
+				i, test.end.Mirror(), mirror)
+		}
+	}
+}
+
+func TestOwareLegal(t *testing.T) {
+	b := &Board{
+		mancala:   OWARE,
+		northPits: []uint{0, 0, 0, 0, 0, 0},
+		southPits: []uint{1, 0, 0, 0, 6, 1},
+	}
+	for pit, legal := range []bool{false, false, false, false, true, true} {
+		if b.Legal(South, uint(pit)) != legal {
+			t.Errorf("Expected legality of %d to be %t", pit, legal)
+		}
+	}
+	if b.Over() {
+		t.Error("South can feed north")
+	}
+
+	b.southPits[4] = 0
+	b.southPits[5] = 0
+	if !b.Over() {
+		t.Error("South cannot feed north")
+	}
+	if b.Outcome(South) != WIN {
+		t.Error("South should win by collecting the remaining seeds")
+	}
+}
+
+func TestOwarePlayout(t *testing.T) {
+	rng := rand.New(rand.NewSource(2671))
+	for n := 0; n < 200; n++ {
+		var (
+			b     = MakeOware()
+			side  = South
+			moves = 0
+		)
+		for !b.Over() {
+			count, _ := b.Moves(side)
+			if count == 0 {
+				t.Fatalf("No moves for %s in %s", side, b)
+			}
+			if b.Sow(side, b.Random(side, rng)) {
+				t.Fatal("Unexpected repeat move")
+			}
+			side = !side
+
+			var seeds uint = b.north + b.south
+			for i := 0; i < OWARE_SIZE; i++ {
+				seeds += b.northPits[i] + b.southPits[i]
+			}
+			if seeds != 2*OWARE_SIZE*OWARE_INIT {
+				t.Fatalf("Seeds were lost in %s", b)
+			}
+
+			moves++
+			if moves > 10000 {
+				t.Fatalf("Game did not end: %s", b)
+			}
+		}
+	}
+}
+
+func TestParseMancala(t *testing.T) {
+	for _, m := range []Mancala{KALAH, OWARE} {
+		p, err := ParseMancala(m.String())
+		if err != nil || p != m {
+			t.Errorf("Could not parse %q", m)
+		}
+	}
+	if _, err := ParseMancala("awale"); err == nil {
+		t.Error("Parsed unknown game")
+	}
+}
@@ -0,0 +1,439 @@
+// Compact Board Representation
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+const (
+	// Maximal number of pits on each side of a packed board
+	PACKED_PITS = 12
+	// Maximal number of stones on a packed board
+	PACKED_STONES = math.MaxUint8
+)
+
+// Packed is a fixed-size representation of a board
+//
+// Unlike a Board, a Packed board is a value that can be copied by
+// assignment without allocating memory, which makes it suitable for
+// searching game trees.  The methods have the same semantics as the
+// methods of a Board with the same name.  Boards with up to
+// PACKED_PITS pits on each side and PACKED_STONES stones in total can
+// be packed, see Pack.
+type Packed struct {
+	// The pits of each side (see sideIndex), ordered like those
+	// of a Board
+	pits [2][PACKED_PITS]uint8
+	// The stores of each side
+	stores [2]uint8
+	// Number of pits on each side
+	size uint8
+	// The initial number of stones in every pit
+	init uint8
+	// Number of stones on the board
+	total uint8
+	// Number of moves since the last capture (only for Oware)
+	idle uint8
+	// The rules used when sowing and collecting
+	rules Rules
+	// The game played on the board
+	mancala Mancala
+}
+
+// Return the index of SIDE in the arrays of a packed board
+func sideIndex(side Side) int {
+	if side == North {
+		return 1
+	}
+	return 0
+}
+
+// Pack converts a board into its compact representation
+func Pack(b *Board) (p Packed, err error) {
+	size := len(b.northPits)
+	if size > PACKED_PITS || len(b.southPits) != size {
+		return p, fmt.Errorf("cannot pack a board with %d pits", size)
+	}
+
+	// Count the stones without overflowing, by stopping as soon
+	// as there are too many
+	var total uint
+	add := func(n uint) {
+		if n > PACKED_STONES || total+n > PACKED_STONES {
+			total = PACKED_STONES + 1
+		} else {
+			total += n
+		}
+	}
+	add(b.north)
+	add(b.south)
+	for i := 0; i < size; i++ {
+		add(b.northPits[i])
+		add(b.southPits[i])
+	}
+	if total > PACKED_STONES || b.init > PACKED_STONES {
+		return p, fmt.Errorf("cannot pack a board with more than %d stones",
+			PACKED_STONES)
+	}
+	if b.idle > math.MaxUint8 {
+		return p, fmt.Errorf("cannot pack %d moves without a capture", b.idle)
+	}
+
+	for i := 0; i < size; i++ {
+		p.pits[sideIndex(North)][i] = uint8(b.northPits[i])
+		p.pits[sideIndex(South)][i] = uint8(b.southPits[i])
+	}
+	p.stores[sideIndex(North)] = uint8(b.north)
+	p.stores[sideIndex(South)] = uint8(b.south)
+	p.size = uint8(size)
+	p.init = uint8(b.init)
+	p.total = uint8(total)
+	p.idle = uint8(b.idle)
+	p.rules = b.rules
+	p.mancala = b.mancala
+	return p, nil
+}
+
+// Unpack converts a packed board back into a Board
+func (p *Packed) Unpack() *Board {
+	b := MakeBoard(uint(p.size), uint(p.init))
+	for i := 0; i < int(p.size); i++ {
+		b.northPits[i] = uint(p.pits[sideIndex(North)][i])
+		b.southPits[i] = uint(p.pits[sideIndex(South)][i])
+	}
+	b.north = uint(p.stores[sideIndex(North)])
+	b.south = uint(p.stores[sideIndex(South)])
+	b.idle = uint(p.idle)
+	b.rules = p.rules
+	b.mancala = p.mancala
+	return b
+}
+
+func (p *Packed) Type() (size, init uint) {
+	return uint(p.size), uint(p.init)
+}
+
+func (p *Packed) Pit(side Side, pit uint) uint {
+	if pit >= uint(p.size) {
+		panic("Illegal access")
+	}
+	return uint(p.pits[sideIndex(side)][pit])
+}
+
+func (p *Packed) Store(side Side) uint {
+	return uint(p.stores[sideIndex(side)])
+}
+
+// Rules returns the rules the board is played with
+func (p *Packed) Rules() Rules {
+	return p.rules
+}
+
+// Mancala returns the game that is played on the board
+func (p *Packed) Mancala() Mancala {
+	return p.mancala
+}
+
+// String converts a board into a KGP representation
+func (p *Packed) String() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<%d,%d,%d", p.size,
+		p.stores[sideIndex(South)], p.stores[sideIndex(North)])
+	for _, pit := range p.pits[sideIndex(South)][:p.size] {
+		fmt.Fprintf(&buf, ",%d", pit)
+	}
+	for _, pit := range p.pits[sideIndex(North)][:p.size] {
+		fmt.Fprintf(&buf, ",%d", pit)
+	}
+	fmt.Fprint(&buf, ">")
+
+	return buf.String()
+}
+
+// Check if the pits of SIDE are all empty
+func (p *Packed) empty(side Side) bool {
+	for _, n := range p.pits[sideIndex(side)][:p.size] {
+		if n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if sowing PIT of SIDE reaches the opponent
+func (p *Packed) feeds(side Side, pit uint) bool {
+	return uint(p.pits[sideIndex(side)][pit]) >= uint(p.size)-pit
+}
+
+// Legal returns true if SIDE may play move PIT
+func (p *Packed) Legal(side Side, pit uint) bool {
+	if pit >= uint(p.size) {
+		panic("Illegal access")
+	}
+	if p.pits[sideIndex(side)][pit] == 0 {
+		return false
+	}
+	if p.mancala == OWARE {
+		return !p.empty(!side) || p.feeds(side, pit)
+	}
+	return true
+}
+
+func (p *Packed) Moves(side Side) (count, last uint) {
+	for i := uint(0); i < uint(p.size); i++ {
+		if p.Legal(side, i) {
+			last = i
+			count++
+		}
+	}
+
+	return
+}
+
+// Sow modifies the board by sowing PIT for player SELF
+func (p *Packed) Sow(self Side, pit uint) bool {
+	if !p.Legal(self, pit) {
+		// Passing P itself would make every board escape
+		panic(fmt.Sprintf("Illegal move %d by %s in %s",
+			pit, self, p.String()))
+	}
+	if p.mancala == OWARE {
+		return p.sowOware(self, pit)
+	}
+
+	var (
+		size   = uint(p.size)
+		own    = sideIndex(self)
+		side   = own
+		pos    = pit + 1
+		stones = p.pits[own][pit]
+	)
+
+	// pick up stones from pit and distribute them
+	p.pits[own][pit] = 0
+	for stones > 0 {
+		if pos == size {
+			if side == own {
+				p.stores[own]++
+				stones--
+			}
+			side = 1 - side
+			pos = 0
+		} else {
+			p.pits[side][pos]++
+			pos++
+			stones--
+		}
+	}
+
+	// check for repeat- or collect-move
+	if pos == 0 && side != own {
+		if p.Over() {
+			p.Collect()
+		}
+		return true
+	} else if side == own && pos > 0 {
+		var (
+			last     = pos - 1
+			opposite = size - 1 - last
+			other    = 1 - own
+		)
+
+		// Capture the stones in the opposite pit, if the
+		// last stone landed in an empty pit
+		var capture bool
+		switch p.rules.Capture {
+		case CAPTURE_OPPOSITE:
+			capture = p.pits[own][last] == 1 && p.pits[other][opposite] > 0
+		case CAPTURE_ALWAYS:
+			capture = p.pits[own][last] == 1
+		}
+		if capture {
+			p.stores[own] += p.pits[other][opposite] + 1
+			p.pits[other][opposite] = 0
+			p.pits[own][last] = 0
+		}
+	}
+
+	if p.Over() {
+		p.Collect()
+	}
+
+	return false
+}
+
+// Sow PIT for player SELF on an Oware board (see Board.sowOware)
+func (p *Packed) sowOware(self Side, pit uint) bool {
+	var (
+		size   = uint(p.size)
+		own    = sideIndex(self)
+		side   = own
+		pos    = pit
+		stones = p.pits[own][pit]
+	)
+
+	p.pits[own][pit] = 0
+	for stones > 0 {
+		pos++
+		if pos == size {
+			side = 1 - side
+			pos = 0
+		}
+		if side == own && pos == pit {
+			continue
+		}
+		p.pits[side][pos]++
+		stones--
+	}
+
+	p.idle++
+	if side != own {
+		var (
+			other = &p.pits[side]
+			seeds uint8
+			taken uint8
+			first = int(pos)
+		)
+		for _, n := range other[:size] {
+			seeds += n
+		}
+		for first >= 0 && (other[first] == 2 || other[first] == 3) {
+			taken += other[first]
+			first--
+		}
+		if taken > 0 && taken < seeds {
+			for i := first + 1; i <= int(pos); i++ {
+				other[i] = 0
+			}
+			p.stores[own] += taken
+			p.idle = 0
+		}
+	}
+
+	if p.Over() {
+		p.Collect()
+	}
+
+	return false
+}
+
+// OverFor returns true if the game has finished for a SIDE
+//
+// The second argument designates the first pit that would be a legal
+// move, iff the game is not over for SIDE.
+func (p *Packed) OverFor(side Side) (bool, uint) {
+	for i, n := range p.pits[sideIndex(side)][:p.size] {
+		if n > 0 {
+			return false, uint(i)
+		}
+	}
+	return true, 0
+}
+
+// Over returns true if the game is over for either side
+func (p *Packed) Over() bool {
+	north, south := p.stores[sideIndex(North)], p.stores[sideIndex(South)]
+	if p.mancala == OWARE {
+		if north > p.total/2 || south > p.total/2 {
+			return true
+		}
+		if p.idle >= OWARE_IDLE {
+			return true
+		}
+		for _, side := range []Side{North, South} {
+			if !p.empty(side) {
+				continue
+			}
+			fed := false
+			for i := uint(0); i < uint(p.size); i++ {
+				fed = fed || p.feeds(!side, i)
+			}
+			if !fed {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !p.rules.NoEarlyWin && (north > p.total/2 || south > p.total/2) {
+		return true
+	}
+	return p.empty(North) || p.empty(South)
+}
+
+// Calculate the outcome for SIDE
+func (p *Packed) Outcome(side Side) Outcome {
+	if !p.Over() {
+		panic("Cannot determine outcome of unfinished game")
+	}
+
+	north, south := p.remaining()
+	north += uint(p.stores[sideIndex(North)])
+	south += uint(p.stores[sideIndex(South)])
+
+	switch {
+	case north > south:
+		if side == North {
+			return WIN
+		}
+		return LOSS
+	case north < south:
+		if side == North {
+			return LOSS
+		}
+		return WIN
+	default:
+		return DRAW
+	}
+}
+
+// Determine how many stones each side collects at the end of a game
+func (p *Packed) remaining() (north, south uint) {
+	for i := 0; i < int(p.size); i++ {
+		north += uint(p.pits[sideIndex(North)][i])
+		south += uint(p.pits[sideIndex(South)][i])
+	}
+
+	if p.mancala == KALAH && p.rules.Remainder == REMAINDER_EMPTIER {
+		if north == 0 {
+			north, south = south, 0
+		} else if south == 0 {
+			north, south = 0, north
+		}
+	}
+
+	return
+}
+
+// Move all stones for SIDE to the Kalah on SIDE
+func (p *Packed) Collect() {
+	if !p.Over() {
+		panic("Stones may not be collected")
+	}
+
+	north, south := p.remaining()
+	p.pits = [2][PACKED_PITS]uint8{}
+	p.stores[sideIndex(North)] += uint8(north)
+	p.stores[sideIndex(South)] += uint8(south)
+}
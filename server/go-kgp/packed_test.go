@@ -0,0 +1,255 @@
+// Compact Board Representation Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	for i, test := range []struct {
+		board *Board
+		fail  bool
+	}{
+		{board: MakeBoard(6, 4)},
+		{board: MakeBoard(12, 10)},
+		{board: MakeOware()},
+		{board: MakeBoard(13, 1), fail: true},
+		{board: MakeBoard(8, 16), fail: true},
+	} {
+		test.board.SetRules(Rules{Capture: CAPTURE_NONE, NoEarlyWin: true})
+		p, err := Pack(test.board)
+		if test.fail {
+			if err == nil {
+				t.Errorf("(%d) Expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("(%d) Failed with %q", i, err)
+			continue
+		}
+
+		b := p.Unpack()
+		if b.String() != test.board.String() || p.String() != b.String() {
+			t.Errorf("(%d) Expected %s, got %s (%s)", i, test.board, b, &p)
+		}
+		if b.Rules() != test.board.Rules() || b.Mancala() != test.board.Mancala() {
+			t.Errorf("(%d) The game or the rules changed", i)
+		}
+		size, init := b.Type()
+		if s, n := test.board.Type(); s != size || n != init {
+			t.Errorf("(%d) Expected (%d, %d), got (%d, %d)",
+				i, s, n, size, init)
+		}
+	}
+
+	// Stones that underflowed cannot be packed
+	b := MakeBoard(2, 1)
+	b.northPits[0] = 1 << 62
+	b.southPits[1] = 1 << 62
+	if _, err := Pack(b); err == nil {
+		t.Errorf("Packed %s", b)
+	}
+}
+
+// Compare the state of BOARD and PACKED
+func comparePacked(board *Board, packed *Packed) error {
+	if board.String() != packed.String() {
+		return fmt.Errorf("expected %s, got %s", board, packed)
+	}
+	if board.idle != uint(packed.idle) {
+		return fmt.Errorf("expected %d idle moves, got %d", board.idle, packed.idle)
+	}
+	if board.Over() != packed.Over() {
+		return fmt.Errorf("expected Over to be %t", board.Over())
+	}
+	if board.Over() {
+		for _, side := range []Side{North, South} {
+			if board.Outcome(side) != packed.Outcome(side) {
+				return fmt.Errorf("expected the outcome %d for %s", board.Outcome(side), side)
+			}
+		}
+		return nil
+	}
+	for _, side := range []Side{North, South} {
+		bc, bl := board.Moves(side)
+		pc, pl := packed.Moves(side)
+		if bc != pc || bl != pl {
+			return fmt.Errorf("expected %d moves for %s, got %d", bc, side, pc)
+		}
+		bo, bf := board.OverFor(side)
+		po, pf := packed.OverFor(side)
+		if bo != po || bf != pf {
+			return fmt.Errorf("expected OverFor(%s) to be %t", side, bo)
+		}
+	}
+	return nil
+}
+
+// Play random moves on both representations and check that they agree
+func TestPackedDifferential(t *testing.T) {
+	moves := 1000000
+	if testing.Short() {
+		moves = 100000
+	}
+
+	var boards []*Board
+	for _, rules := range []Rules{
+		{},
+		{Capture: CAPTURE_ALWAYS},
+		{Capture: CAPTURE_NONE},
+		{Remainder: REMAINDER_EMPTIER},
+		{NoEarlyWin: true},
+		{Capture: CAPTURE_ALWAYS, Remainder: REMAINDER_EMPTIER, NoEarlyWin: true},
+	} {
+		for _, size := range []uint{1, 2, 3, 6, 8, 12} {
+			for _, init := range []uint{1, 3, 4, 8, 10} {
+				if 2*size*init > PACKED_STONES {
+					continue
+				}
+				b := MakeBoard(size, init)
+				b.SetRules(rules)
+				boards = append(boards, b)
+			}
+		}
+	}
+	boards = append(boards, MakeOware())
+
+	rng := rand.New(rand.NewSource(2671))
+	for n := 0; n < moves; {
+		var (
+			board  = boards[rng.Intn(len(boards))].Copy()
+			side   = South
+			packed Packed
+			err    error
+		)
+		packed, err = Pack(board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for !board.Over() {
+			var (
+				move = board.Random(side, rng)
+				prev = packed
+			)
+			brep := board.Sow(side, move)
+			prep := packed.Sow(side, move)
+			if brep != prep {
+				t.Fatalf("Sowing %d by %s on %s: expected the repeat flag to be %t",
+					move, side, &prev, brep)
+			}
+			if err := comparePacked(board, &packed); err != nil {
+				t.Fatalf("Sowing %d by %s on %s (%s %s): %s",
+					move, side, &prev, board.Mancala(), board.Rules(), err)
+			}
+			if !brep {
+				side = !side
+			}
+			n++
+		}
+	}
+}
+
+// Play a random game from BOARD on a copy, using RNG
+func playoutBoard(board *Board, rng *rand.Rand) {
+	b := board.Copy()
+	side := South
+	for !b.Over() {
+		if !b.Sow(side, b.Random(side, rng)) {
+			side = !side
+		}
+	}
+}
+
+// Play a random game from BOARD on a copy, using RNG
+func playoutPacked(board Packed, rng *rand.Rand) {
+	var (
+		side  = South
+		moves [PACKED_PITS]uint
+	)
+	for !board.Over() {
+		n := 0
+		for i := uint(0); i < uint(board.size); i++ {
+			if board.Legal(side, i) {
+				moves[n] = i
+				n++
+			}
+		}
+		if !board.Sow(side, moves[rng.Intn(n)]) {
+			side = !side
+		}
+	}
+}
+
+func BenchmarkSow(b *testing.B) {
+	board := MakeBoard(8, 8)
+	packed, err := Pack(board)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("board", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c := board.Copy()
+			c.Sow(South, uint(i%8))
+		}
+	})
+	b.Run("packed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c := packed
+			c.Sow(South, uint(i%8))
+		}
+	})
+}
+
+func BenchmarkPlayout(b *testing.B) {
+	for _, board := range []*Board{
+		MakeBoard(6, 4),
+		MakeBoard(8, 8),
+		MakeOware(),
+	} {
+		packed, err := Pack(board)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size, init := board.Type()
+		name := fmt.Sprintf("%s_%dx%d", board.Mancala(), size, init)
+
+		b.Run(name+"_board", func(b *testing.B) {
+			rng := rand.New(rand.NewSource(2671))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				playoutBoard(board, rng)
+			}
+		})
+		b.Run(name+"_packed", func(b *testing.B) {
+			rng := rand.New(rand.NewSource(2671))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				playoutPacked(packed, rng)
+			}
+		})
+	}
+}
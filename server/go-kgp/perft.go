@@ -0,0 +1,78 @@
+// Move Tree Counting
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import "fmt"
+
+// Perft is the result of counting the positions in a move tree
+type Perft struct {
+	// Number of moves that were made
+	Nodes uint64
+	// Number of unfinished positions at the maximal depth
+	Leaves uint64
+	// Number of finished games within the maximal depth
+	Terminal uint64
+	// Number of moves after which the same side moves again
+	Repeats uint64
+}
+
+// Add the counts of O to P
+func (p *Perft) Add(o Perft) {
+	p.Nodes += o.Nodes
+	p.Leaves += o.Leaves
+	p.Terminal += o.Terminal
+	p.Repeats += o.Repeats
+}
+
+func (p Perft) String() string {
+	return fmt.Sprintf("%d nodes, %d leaves, %d terminal, %d repeats",
+		p.Nodes, p.Leaves, p.Terminal, p.Repeats)
+}
+
+// Perft counts all positions reachable within DEPTH moves, when SIDE
+// is to move
+//
+// Every sowing is counted as a move, so that after a repeat-move the
+// same side makes the next move at a greater depth.
+func (p *Packed) Perft(side Side, depth uint) (c Perft) {
+	if p.Over() {
+		c.Terminal++
+		return
+	}
+	if depth == 0 {
+		c.Leaves++
+		return
+	}
+
+	for m := uint(0); m < uint(p.size); m++ {
+		if !p.Legal(side, m) {
+			continue
+		}
+
+		n, next := *p, !side
+		if n.Sow(side, m) {
+			c.Repeats++
+			next = side
+		}
+		c.Nodes++
+		c.Add(n.Perft(next, depth-1))
+	}
+	return
+}
@@ -0,0 +1,206 @@
+// Move Tree Counting Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Reference counts, that any change to the rules of sowing must
+// preserve
+var perftReference = []struct {
+	board   string
+	rules   string
+	mancala Mancala
+	side    Side
+	depth   uint
+	count   Perft
+}{
+	{
+		board: "<1,0,0,1,1>",
+		depth: 3,
+		count: Perft{Nodes: 1, Leaves: 0, Terminal: 1, Repeats: 1},
+	},
+	{
+		board: "<2,0,0,2,2,2,2>",
+		depth: 7,
+		count: Perft{Nodes: 24, Leaves: 1, Terminal: 6, Repeats: 6},
+	},
+	{
+		board: "<3,0,0,3,3,3,3,3,3>",
+		depth: 7,
+		count: Perft{Nodes: 1080, Leaves: 581, Terminal: 27, Repeats: 202},
+	},
+	{
+		board: "<3,0,0,3,3,3,3,3,3>",
+		rules: "capture=none",
+		depth: 7,
+		count: Perft{Nodes: 1151, Leaves: 663, Terminal: 1, Repeats: 212},
+	},
+	{
+		board: "<3,0,0,3,3,3,3,3,3>",
+		rules: "remainder=emptier earlywin=no",
+		depth: 7,
+		count: Perft{Nodes: 1088, Leaves: 599, Terminal: 12, Repeats: 203},
+	},
+	{
+		board: "<4,0,0,4,4,4,4,4,4,4,4>",
+		depth: 7,
+		count: Perft{Nodes: 10568, Leaves: 7470, Terminal: 0, Repeats: 1125},
+	},
+	{
+		board: "<6,0,0,4,4,4,4,4,4,4,4,4,4,4,4>",
+		depth: 6,
+		count: Perft{Nodes: 29091, Leaves: 23233, Terminal: 0, Repeats: 4373},
+	},
+	{
+		board: "<6,10,8,0,5,2,0,7,1,3,0,6,2,0,4>",
+		side:  North,
+		depth: 6,
+		count: Perft{Nodes: 7741, Leaves: 5936, Terminal: 0, Repeats: 1001},
+	},
+	{
+		board: "<6,10,8,0,5,2,0,7,1,3,0,6,2,0,4>",
+		rules: "capture=always",
+		side:  North,
+		depth: 6,
+		count: Perft{Nodes: 7683, Leaves: 5884, Terminal: 0, Repeats: 982},
+	},
+	{
+		board: "<6,0,0,6,6,6,6,6,6,6,6,6,6,6,6>",
+		depth: 6,
+		count: Perft{Nodes: 39412, Leaves: 32243, Terminal: 0, Repeats: 1475},
+	},
+	{
+		board: "<8,0,0,8,8,8,8,8,8,8,8,8,8,8,8,8,8,8,8>",
+		depth: 5,
+		count: Perft{Nodes: 30853, Leaves: 26770, Terminal: 0, Repeats: 603},
+	},
+	{
+		board: "<12,0,0,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10,10>",
+		depth: 4,
+		count: Perft{Nodes: 20084, Leaves: 18307, Terminal: 0, Repeats: 1700},
+	},
+	{
+		board:   "<6,0,0,4,4,4,4,4,4,4,4,4,4,4,4>",
+		mancala: OWARE,
+		depth:   6,
+		count:   Perft{Nodes: 33797, Leaves: 27332, Terminal: 0, Repeats: 0},
+	},
+	{
+		board:   "<6,10,12,0,1,2,0,3,2,1,0,0,5,2,10>",
+		mancala: OWARE,
+		depth:   6,
+		count:   Perft{Nodes: 7516, Leaves: 5736, Terminal: 0, Repeats: 0},
+	},
+}
+
+// Count the move tree on a Board, independently of Packed.Perft
+func boardPerft(b *Board, side Side, depth uint) (c Perft) {
+	if b.Over() {
+		c.Terminal++
+		return
+	}
+	if depth == 0 {
+		c.Leaves++
+		return
+	}
+
+	size, _ := b.Type()
+	for m := uint(0); m < size; m++ {
+		if !b.Legal(side, m) {
+			continue
+		}
+
+		n, next := b.Copy(), !side
+		if n.Sow(side, m) {
+			c.Repeats++
+			next = side
+		}
+		c.Nodes++
+		c.Add(boardPerft(n, next, depth-1))
+	}
+	return
+}
+
+// Parse the board of a reference count
+func perftBoard(spec, rules string, mancala Mancala) (*Board, error) {
+	b, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	b.SetRules(r)
+	b.SetMancala(mancala)
+	return b, b.Validate(b.Type())
+}
+
+func TestPerft(t *testing.T) {
+	for i, test := range perftReference {
+		b, err := perftBoard(test.board, test.rules, test.mancala)
+		if err != nil {
+			t.Fatalf("(%d) Invalid board %s: %s", i, test.board, err)
+		}
+		p, err := Pack(b)
+		if err != nil {
+			t.Fatalf("(%d) Failed to pack %s: %s", i, b, err)
+		}
+
+		if c := p.Perft(test.side, test.depth); c != test.count {
+			t.Errorf("(%d) Expected %s, got %s", i, test.count, c)
+		}
+		if c := boardPerft(b, test.side, test.depth); c != test.count {
+			t.Errorf("(%d) Expected %s on the board, got %s",
+				i, test.count, c)
+		}
+	}
+
+	// A finished game is a terminal position
+	b, _ := Parse("<2,4,0,0,0,0,0>")
+	p, _ := Pack(b)
+	if c := p.Perft(South, 3); c != (Perft{Terminal: 1}) {
+		t.Errorf("Expected a single terminal position, got %s", c)
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	for _, test := range perftReference {
+		board, err := perftBoard(test.board, test.rules, test.mancala)
+		if err != nil {
+			b.Fatal(err)
+		}
+		p, err := Pack(board)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		size, init := board.Type()
+		name := fmt.Sprintf("%s_%dx%d_%d", board.Mancala(), size, init, test.depth)
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p.Perft(test.side, test.depth)
+			}
+		})
+	}
+}
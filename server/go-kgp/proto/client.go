@@ -25,6 +25,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,6 +34,7 @@ import (
 
 	"go-kgp"
 	"go-kgp/conf"
+	"go-kgp/msg"
 )
 
 var defaultUser = &kgp.User{Descr: `Pseudo-user of all unidentified agents.`}
@@ -66,34 +69,129 @@ type client struct {
 	resp   chan *response
 	init   bool
 	comm   string
+	bye    uint32        // did the client say goodbye (actually bool)
+	authed bool          // was the token set using "auth:token"
+	closed chan struct{} // closed with the connection
+	gid    uint64        // last game:id sent to the client
+	game   kgp.Mancala   // game requested by the client
+	size   uint          // number of pits requested by the client
+	stones uint          // number of stones requested by the client
+
+	// Connection limits (see limit.go)
+	addr      net.IP    // remote address, if known
+	token     string    // token counted towards the limit
+	allowance float64   // number of commands that may be issued
+	checked   time.Time // last time the allowance was updated
+	throttled bool      // has the client exceeded the rate limit
+
+	// Reconnection state (see resume.go)
+	next     *client       // client that claimed this client
+	claimed  chan struct{} // closed when claimed
+	deadline time.Time     // until when this client may be claimed
 }
 
 func MakeClient(rwc io.ReadWriteCloser, conf *conf.Conf) {
-	go (&client{
-		user:  defaultUser,
-		games: make(map[uint64]*kgp.Game),
-		req:   make(chan *request, 1),
-		resp:  make(chan *response, 1),
-		rwc:   rwc,
-		conf:  conf,
-	}).handle()
+	cli := &client{
+		user:    defaultUser,
+		games:   make(map[uint64]*kgp.Game),
+		req:     make(chan *request, 1),
+		resp:    make(chan *response, 1),
+		rwc:     rwc,
+		conf:    conf,
+		closed:  make(chan struct{}),
+		claimed: make(chan struct{}),
+	}
+	if c, ok := rwc.(interface{ RemoteAddr() net.Addr }); ok {
+		cli.addr = remoteIP(c.RemoteAddr())
+	}
+	go cli.handle()
 }
 
 func (cli *client) User() *kgp.User {
 	return cli.user
 }
 
+// Mancala returns the game the client has requested to play
+func (cli *client) Mancala() kgp.Mancala {
+	return cli.game
+}
+
+// Configuration returns the board the client has requested to play on
+//
+// Zero indicates that the client has no preference.
+func (cli *client) Configuration() (size, init uint) {
+	return cli.size, cli.stones
+}
+
+// Check if the board requested by the client can be played on
+func (cli *client) configurable() bool {
+	if cli.game == kgp.OWARE {
+		return (cli.size == 0 || cli.size == kgp.OWARE_SIZE) &&
+			(cli.stones == 0 || cli.stones == kgp.OWARE_INIT)
+	}
+	return cli.conf.Supports(cli.size, cli.stones)
+}
+
 // Request a client to make a move
 func (cli *client) Request(game *kgp.Game) (*kgp.Move, bool) {
-	if cli.rwc == nil {
+	return cli.request(game, cli, time.Now().Add(cli.conf.MoveTimeout))
+}
+
+// Request a move from CLI for the agent AGENT in GAME until DEADLINE
+//
+// If the connection to CLI has been lost, the request is forwarded
+// to a client that has reconnected in place of CLI.
+func (cli *client) request(game *kgp.Game, agent kgp.Agent, deadline time.Time) (*kgp.Move, bool) {
+	var (
+		move    *kgp.Move // last move proposed by the client
+		start   = time.Now()
+		side    = game.Side(agent)
+		timeout = time.After(time.Until(deadline))
+	)
+
+	// If the client did not propose a move, a random move is made
+	// instead.  It is only drawn when necessary, so that the
+	// random decisions of a game only depend on the seed of the
+	// game and the moves that were not decided in time.
+	decide := func() (*kgp.Move, bool) {
+		if move == nil {
+			move = &kgp.Move{
+				Choice:  game.Board.Random(side, game.Rand()),
+				Comment: "[random move]",
+				Agent:   agent,
+				Game:    game,
+				Stamp:   start,
+				Source:  kgp.FALLBACK,
+			}
+		}
+		move.Think = time.Since(start)
+		return move, false
+	}
+
+	if !cli.connected() {
+		if next := cli.successor(timeout); next != nil {
+			return next.request(game, agent, deadline)
+		}
+		// A client that may still reconnect only misses
+		// this move
+		if time.Now().Before(cli.expires()) {
+			return decide()
+		}
 		return nil, true
 	}
 
 	c := make(chan *kgp.Move, 1)
 	board := game.Board
-	if game.North == cli {
+	if side == kgp.North {
 		board = board.Mirror()
 	}
+	if cli.gid != game.Id {
+		cli.send("set", "game:id", strconv.FormatUint(game.Id, 10))
+		if game.Board.Mancala() == kgp.KALAH {
+			cli.send("set", "game:rules", game.Board.Rules().String())
+		}
+		cli.gid = game.Id
+	}
 	id := cli.send("state", board)
 	defer cli.respond(id, "stop")
 
@@ -103,33 +201,58 @@ func (cli *client) Request(game *kgp.Game) (*kgp.Move, bool) {
 
 	cli.req <- &request{c, id}
 
-	move := &kgp.Move{
-		Choice:  game.Board.Random(game.Side(cli)),
-		Comment: "[random move]",
-		Agent:   cli,
-		Game:    game,
-		Stamp:   time.Now(),
-	}
-
 	for {
 		select {
-		case <-time.After(cli.conf.MoveTimeout):
-			return move, false
+		case <-timeout:
+			if move != nil {
+				return move, false
+			}
+			return decide()
+		case <-cli.closed:
+			// If the connection was lost while the
+			// client was thinking, the request is
+			// repeated for the client that reconnects,
+			// as long as there is time left.
+			if next := cli.successor(timeout); next != nil {
+				return next.request(game, agent, deadline)
+			}
+			return decide()
 		case m := <-c:
 			if m == nil {
-				return move, false
+				return decide()
 			}
+			m.Agent = agent
+			m.Think = time.Since(start)
 			move = m
 		}
 	}
 }
 
-func (cli *client) Alive() bool {
-	defer cli.iolock.Unlock()
+// Connected returns true if CLI currently has a connection
+func (cli *client) connected() bool {
 	cli.iolock.Lock()
+	defer cli.iolock.Unlock()
 	return cli.rwc != nil
 }
 
+// Alive returns true if the client is connected or may reconnect
+func (cli *client) Alive() bool {
+	cli.iolock.Lock()
+	if cli.rwc != nil {
+		cli.iolock.Unlock()
+		return true
+	}
+	deadline := cli.deadline
+	cli.iolock.Unlock()
+
+	select {
+	case <-cli.claimed:
+		return cli.next.Alive()
+	default:
+		return time.Now().Before(deadline)
+	}
+}
+
 // String will return a string representation for a client for
 // internal use
 func (cli *client) String() string {
@@ -149,8 +272,8 @@ func (cli *client) error(to uint64, args ...interface{}) {
 // Respond forwards a referenced message to the client
 //
 // Each element in ARGS is handled as an argument to COMMAND, and will
-// use the concrete datatype for formatting.  Respond does not check
-// if the arguments have the right types for COMMAND.
+// use the concrete datatype for formatting (see msg.Make).  Respond
+// does not check if the arguments have the right types for COMMAND.
 //
 // If TO is 0, no reference will be added.
 func (cli *client) respond(to uint64, command string, args ...interface{}) uint64 {
@@ -163,30 +286,7 @@ func (cli *client) respond(to uint64, command string, args ...interface{}) uint6
 		id  = atomic.AddUint64(&cli.rid, 2)
 	)
 
-	fmt.Fprint(&buf, id)
-	if to > 0 {
-		fmt.Fprintf(&buf, "@%d", to)
-	}
-
-	fmt.Fprintf(&buf, " %s", command)
-
-	for _, arg := range args {
-		fmt.Fprint(&buf, " ")
-		switch v := arg.(type) {
-		case string:
-			fmt.Fprintf(&buf, "%#v", v)
-		case int:
-			fmt.Fprintf(&buf, "%d", v)
-		case float64:
-			fmt.Fprintf(&buf, "%f", v)
-		case *kgp.Board:
-			fmt.Fprint(&buf, v.String())
-		case *kgp.Game:
-			fmt.Fprint(&buf, v.State.String())
-		default:
-			panic(fmt.Sprintf("Unsupported type: %T", arg))
-		}
-	}
+	fmt.Fprint(&buf, msg.Make(id, to, command, args...))
 
 	// attempt to send this message before any other message is sent
 	defer cli.iolock.Unlock()
@@ -222,7 +322,7 @@ func (cli *client) pinger(ctx context.Context) {
 		case <-ticker.C:
 			// If the timer fired, check the ping flag and
 			// kill the client if it is still set
-			if cli.rwc == nil {
+			if !cli.connected() {
 				continue
 			}
 		}
@@ -266,9 +366,22 @@ func (cli *client) handle() {
 
 	var ctx context.Context
 	ctx, cli.kill = context.WithCancel(context.Background())
+	defer cli.kill()
+
+	if err := register(cli); err != nil {
+		if err != errShutdown {
+			cli.conf.Log.Printf("Rejecting connection from %s: %s", cli.addr, err)
+		}
+		fmt.Fprintf(cli.rwc, "error %s\r\ngoodbye\r\n", msg.Quote(err.Error()))
+		atomic.StoreUint32(&cli.bye, 1)
+		close(cli.closed)
+		return
+	}
+	defer unregister(cli)
 
 	// Initiate the protocol with the client
-	cli.send("kgp", majorVersion, minorVersion, patchVersion)
+	v := msg.Current
+	cli.send("kgp", v.Major, v.Minor, v.Patch)
 
 	// Optionally start a thread to periodically send ping
 	// requests to the client
@@ -280,19 +393,23 @@ func (cli *client) handle() {
 	}
 
 	// Start a thread to read the user input from rwc
-	dead := false
+	var dead uint32 // actually bool
 	go func() {
 		scanner := bufio.NewScanner(cli.rwc)
+		scanner.Split(msg.SplitCommands())
 		for scanner.Scan() {
 			// Check if the client has been killed
 			// by someone else
-			if dead {
+			if atomic.LoadUint32(&dead) != 0 {
 				break
 			}
 
 			// Interpret line
 			input := scanner.Text()
 			dbg(cli, "<", input)
+			if !cli.allow() {
+				continue
+			}
 			err := cli.interpret(input)
 			if err != nil {
 				cli.conf.Log.Print(err)
@@ -347,17 +464,21 @@ func (cli *client) handle() {
 	}
 shutdown:
 
-	// Request for the client to be removed from the queue
-	cli.conf.GM.Unschedule(cli)
+	// Authenticated clients that have not said goodbye may
+	// reconnect and resume their game, otherwise request for the
+	// client to be removed from the queue.
+	reconnect := atomic.LoadUint32(&cli.bye) == 0 && cli.authed && cli.conf.ReconnectTimeout > 0
+	if !reconnect {
+		cli.conf.GM.Unschedule(cli)
+	}
 
 	// Send a simple goodbye, ignoring errors if the network
 	// connection was broken
-	defer cli.iolock.Unlock()
 	cli.iolock.Lock()
 	fmt.Fprint(rwc, "goodbye\r\n")
 
 	// Kill input processing thread
-	dead = true
+	atomic.StoreUint32(&dead, 1)
 
 	// Kill ping thread if requested for the connection
 	if done != nil {
@@ -366,6 +487,15 @@ shutdown:
 
 	// Unset the ReadWriteCloser
 	cli.rwc = nil
+	if reconnect {
+		cli.deadline = time.Now().Add(cli.conf.ReconnectTimeout)
+	}
+	cli.iolock.Unlock()
+
+	if reconnect {
+		cli.wait()
+	}
+	close(cli.closed)
 
 	dbg("Closed connection to", cli)
 }
@@ -0,0 +1,253 @@
+// Connection limits, rate limiting and bans
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package proto
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-kgp"
+	"go-kgp/conf"
+)
+
+// Reasons for rejecting a client
+var (
+	errShutdown     = errors.New("Server is shutting down")
+	errBanned       = errors.New("Access denied")
+	errTooManyIP    = errors.New("Too many connections from this address")
+	errTooManyToken = errors.New("Too many connections using this token")
+	errReserved     = errors.New("This token is reserved for bots")
+)
+
+var (
+	// Number of connections per IP address and token
+	perIP    = make(map[string]uint)
+	perToken = make(map[string]uint)
+	// Currently banned tokens and networks
+	bans = &banlist{}
+)
+
+// List of banned tokens and networks
+type banlist struct {
+	tokens map[string]struct{}
+	nets   []*net.IPNet
+}
+
+// Parse a ban list from R
+//
+// Each line either contains an IP address, a network in CIDR
+// notation or a token.  Empty lines and lines starting with a "#"
+// are ignored.
+func parseBans(r io.Reader) (*banlist, error) {
+	b := &banlist{tokens: make(map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, ipnet, err := net.ParseCIDR(line); err == nil {
+			b.nets = append(b.nets, ipnet)
+		} else if ip := net.ParseIP(line); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			b.nets = append(b.nets, &net.IPNet{
+				IP:   ip,
+				Mask: net.CIDRMask(bits, bits),
+			})
+		} else {
+			b.tokens[line] = struct{}{}
+		}
+	}
+
+	return b, scanner.Err()
+}
+
+// Check if CLI has been banned, either by address or by token
+func (b *banlist) banned(cli *client) bool {
+	if _, ok := b.tokens[cli.token]; ok && cli.token != "" {
+		return true
+	}
+	if cli.addr != nil {
+		for _, n := range b.nets {
+			if n.Contains(cli.addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Load the ban list and disconnect all banned clients
+func loadBans(conf *conf.Conf) error {
+	b := &banlist{}
+	if conf.BanFile != "" {
+		file, err := os.Open(conf.BanFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		b, err = parseBans(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	defer clock.Unlock()
+	clock.Lock()
+
+	bans = b
+	for cli := range clients {
+		if bans.banned(cli) {
+			cli.reject(errBanned)
+		}
+	}
+	return nil
+}
+
+// Determine the IP address of a remote address
+func remoteIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return nil
+		}
+		return net.ParseIP(host)
+	}
+}
+
+// Account for CLI connecting with TOKEN
+//
+// The caller must hold clock.
+func (cli *client) authenticate(token string) error {
+	if _, ok := bans.tokens[token]; ok {
+		return errBanned
+	}
+	if reserved(token) {
+		return errReserved
+	}
+	if token == cli.token {
+		return nil
+	}
+	max := cli.conf.MaxConnToken
+	if max > 0 && perToken[token] >= max {
+		return errTooManyToken
+	}
+
+	release(perToken, cli.token)
+	perToken[token]++
+	cli.token = token
+	return nil
+}
+
+// Identify an unauthenticated CLI by VAL
+//
+// Clients that did not set a token are identified by the first
+// information they provide.  If VAL may not be used as a token, the
+// client is rejected and false is returned.
+func (cli *client) anonymous(val string) bool {
+	if cli.user != defaultUser {
+		return true
+	}
+	if reserved(val) {
+		cli.reject(errReserved)
+		return false
+	}
+	cli.user = &kgp.User{Token: val}
+	return true
+}
+
+// Check if TOKEN may only be used by bots
+func reserved(token string) bool {
+	return strings.HasPrefix(token, kgp.BOT_TOKEN_PREFIX)
+}
+
+// Decrement the connection count of KEY in COUNT
+func release(count map[string]uint, key string) {
+	if key == "" {
+		return
+	}
+	if count[key] <= 1 {
+		delete(count, key)
+	} else {
+		count[key]--
+	}
+}
+
+// Report ERR to CLI and disconnect the client
+func (cli *client) reject(err error) {
+	cli.conf.Log.Printf("Disconnecting %s (%s): %s", cli, cli.addr, err)
+	cli.error(0, err.Error())
+	atomic.StoreUint32(&cli.bye, 1)
+	cli.kill()
+}
+
+// Check if CLI may issue another command
+//
+// Each client may issue CommandRate commands per second, and in
+// addition to that a burst of up to CommandBurst commands.  Once a
+// client exceeds the limit, commands are dropped and the client is
+// notified once.
+func (cli *client) allow() bool {
+	rate := float64(cli.conf.CommandRate)
+	if rate == 0 {
+		return true
+	}
+
+	var (
+		now = time.Now()
+		max = rate + float64(cli.conf.CommandBurst)
+	)
+	if cli.checked.IsZero() {
+		cli.allowance = max
+	} else {
+		cli.allowance += now.Sub(cli.checked).Seconds() * rate
+		if cli.allowance > max {
+			cli.allowance = max
+		}
+	}
+	cli.checked = now
+
+	if cli.allowance < 1 {
+		if !cli.throttled {
+			cli.conf.Log.Printf("Throttling %s (%s)", cli, cli.addr)
+			cli.error(0, "Rate limit exceeded, dropping commands")
+			cli.throttled = true
+		}
+		return false
+	}
+	cli.allowance--
+	cli.throttled = false
+	return true
+}
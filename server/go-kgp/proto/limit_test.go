@@ -0,0 +1,139 @@
+// Connection Limit Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package proto
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"go-kgp/conf"
+)
+
+func TestBans(t *testing.T) {
+	b, err := parseBans(strings.NewReader(`
+# comments and empty lines are ignored
+
+10.0.0.0/8
+192.168.1.1
+2001:db8::/32
+some secret token
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		addr   string
+		token  string
+		banned bool
+	}{
+		{addr: "10.1.2.3", banned: true},
+		{addr: "11.1.2.3", banned: false},
+		{addr: "192.168.1.1", banned: true},
+		{addr: "192.168.1.2", banned: false},
+		{addr: "2001:db8::1", banned: true},
+		{addr: "2001:db9::1", banned: false},
+		{token: "some secret token", banned: true},
+		{token: "# comments and empty lines are ignored", banned: false},
+		{addr: "11.1.2.3", token: "some secret token", banned: true},
+		{},
+	} {
+		cli := &client{addr: net.ParseIP(test.addr), token: test.token}
+		if b.banned(cli) != test.banned {
+			t.Errorf("(%d) Expected %q/%q to be banned: %t",
+				i, test.addr, test.token, test.banned)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	config := conf.Default(false)
+	config.MaxConnIP = 2
+	config.MaxConnToken = 1
+
+	var (
+		addr = net.ParseIP("127.0.0.1")
+		a    = &client{conf: config, addr: addr}
+		b    = &client{conf: config, addr: addr}
+		c    = &client{conf: config, addr: addr}
+	)
+
+	if err := register(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := register(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := register(c); err != errTooManyIP {
+		t.Fatalf("Expected %q, got %v", errTooManyIP, err)
+	}
+
+	clock.Lock()
+	if err := a.authenticate("token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.authenticate("token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.authenticate("token"); err != errTooManyToken {
+		t.Fatalf("Expected %q, got %v", errTooManyToken, err)
+	}
+	if err := b.authenticate("bot:MinMax-2"); err != errReserved {
+		t.Fatalf("Expected %q, got %v", errReserved, err)
+	}
+	clock.Unlock()
+
+	// Disconnecting releases both the address and the token
+	unregister(a)
+	if err := register(c); err != nil {
+		t.Fatal(err)
+	}
+	clock.Lock()
+	if err := b.authenticate("token"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Unlock()
+
+	unregister(b)
+	unregister(c)
+	if len(perIP) != 0 || len(perToken) != 0 {
+		t.Fatalf("Connections were not released: %v, %v", perIP, perToken)
+	}
+}
+
+func TestRate(t *testing.T) {
+	config := conf.Default(false)
+	config.CommandRate = 10
+	config.CommandBurst = 5
+
+	cli := &client{conf: config}
+	for i := 0; i < 15; i++ {
+		if !cli.allow() {
+			t.Fatalf("Command %d was not allowed", i)
+		}
+	}
+	if cli.allow() {
+		t.Fatal("Rate limit was not enforced")
+	}
+	if !cli.throttled {
+		t.Fatal("Client was not marked as throttled")
+	}
+}
@@ -20,8 +20,15 @@
 package proto
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"go-kgp/conf"
 )
@@ -29,9 +36,13 @@ import (
 type tcp struct {
 	conf *conf.Conf
 	conn net.Listener
+	cert *certificate // only set for TLS connections
 }
 
-func (*tcp) String() string {
+func (t *tcp) String() string {
+	if t.cert != nil {
+		return "TLS Handler"
+	}
 	return "TCP Handler"
 }
 
@@ -41,8 +52,12 @@ func (t *tcp) Start() {
 	}
 
 	var err error
-	tcp := fmt.Sprintf(":%d", t.conf.TCPPort)
-	t.conn, err = net.Listen("tcp", tcp)
+	port := t.conf.TCPPort
+	if t.cert != nil {
+		port = t.conf.TLSPort
+	}
+	tcp := fmt.Sprintf(":%d", port)
+	t.conn, err = t.listen(tcp)
 	if err != nil {
 		t.conf.Log.Fatal(err)
 	}
@@ -50,7 +65,10 @@ func (t *tcp) Start() {
 	t.conf.Debug.Printf("Accepting connections on %s", tcp)
 	for {
 		conn, err := t.conn.Accept()
-		if err != nil {
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			t.conf.Log.Print(err)
 			continue
 		}
 
@@ -58,7 +76,25 @@ func (t *tcp) Start() {
 	}
 }
 
+// Open a listener on ADDR, optionally wrapped in TLS
+func (t *tcp) listen(addr string) (net.Listener, error) {
+	conn, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if t.cert != nil {
+		conn = tls.NewListener(conn, &tls.Config{
+			GetCertificate: t.cert.get,
+			MinVersion:     tls.VersionTLS12,
+		})
+	}
+	return conn, nil
+}
+
 func (t *tcp) Shutdown() {
+	if t.cert != nil {
+		t.cert.stop()
+	}
 	if err := t.conn.Close(); err != nil {
 		t.conf.Log.Print(err)
 	}
@@ -66,4 +102,117 @@ func (t *tcp) Shutdown() {
 
 func Prepare(conf *conf.Conf) {
 	conf.Register(&tcp{conf: conf})
+
+	// Optionally accept encrypted connections as well
+	switch {
+	case conf.TLSCert == "" && conf.TLSKey == "":
+		return
+	case conf.TLSCert == "" || conf.TLSKey == "":
+		conf.Log.Fatal("TLS requires both a certificate and a key")
+	}
+	cert, err := loadCertificate(conf)
+	if err != nil {
+		conf.Log.Fatal(err)
+	}
+	conf.Register(&tcp{conf: conf, cert: cert})
+}
+
+var (
+	// All connected clients
+	clients = make(map[*client]struct{})
+	// Are clients still being accepted
+	closing bool
+	// Lock for the client registry
+	clock sync.Mutex
+	// Number of clients that are still being handled
+	handling sync.WaitGroup
+)
+
+// Add CLI to the client registry
+//
+// If the server is shutting down, the client has been banned or
+// there are too many connections from the same address, the client
+// is rejected and an error is returned.
+func register(cli *client) error {
+	defer clock.Unlock()
+	clock.Lock()
+
+	if closing {
+		return errShutdown
+	}
+	if bans.banned(cli) {
+		return errBanned
+	}
+	if cli.addr != nil {
+		ip, max := cli.addr.String(), cli.conf.MaxConnIP
+		if max > 0 && perIP[ip] >= max {
+			return errTooManyIP
+		}
+		perIP[ip]++
+	}
+	clients[cli] = struct{}{}
+	handling.Add(1)
+	return nil
+}
+
+// Remove CLI from the client registry
+func unregister(cli *client) {
+	clock.Lock()
+	delete(clients, cli)
+	if cli.addr != nil {
+		release(perIP, cli.addr.String())
+	}
+	release(perToken, cli.token)
+	clock.Unlock()
+	handling.Done()
+}
+
+// The client manager reloads the ban list on SIGHUP and disconnects
+// all clients during a shutdown
+type manager struct {
+	conf *conf.Conf
+	hup  chan os.Signal
+}
+
+func (*manager) String() string { return "Client Manager" }
+
+func (m *manager) Start() {
+	signal.Notify(m.hup, syscall.SIGHUP)
+	for range m.hup {
+		m.conf.Log.Print("Reloading ban list")
+		if err := loadBans(m.conf); err != nil {
+			m.conf.Log.Print(err)
+		}
+	}
+}
+
+func (m *manager) Shutdown() {
+	signal.Stop(m.hup)
+	close(m.hup)
+
+	clock.Lock()
+	closing = true
+	for cli := range clients {
+		atomic.StoreUint32(&cli.bye, 1)
+		cli.error(0, errShutdown.Error())
+		cli.kill()
+	}
+	clock.Unlock()
+
+	m.conf.Debug.Println("Waiting for clients to disconnect")
+	handling.Wait()
+}
+
+// Register a manager to disconnect all clients during a shutdown
+//
+// The manager should be registered before the game manager, so that
+// clients remain connected while running games are finishing.
+func PrepareClients(conf *conf.Conf) {
+	if err := loadBans(conf); err != nil {
+		conf.Log.Fatal(err)
+	}
+	conf.Register(&manager{
+		conf: conf,
+		hup:  make(chan os.Signal, 1),
+	})
 }
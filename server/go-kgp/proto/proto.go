@@ -20,157 +20,81 @@
 package proto
 
 import (
-	"errors"
-	"regexp"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
-	"unicode"
 
 	"go-kgp"
+	"go-kgp/msg"
 )
 
-const (
-	majorVersion = 1
-	minorVersion = 0
-	patchVersion = 1
-)
-
-var (
-	// Regular expression to destruct a command
-	tokenizer = regexp.MustCompile(`^[[:space:]]*` +
-		`(?:([[:digit:]]*)(?:@([[:digit:]]+))?[[:space:]]+)?` +
-		`([[:alnum:]]+)(?:[[:space:]]+(.*))?` +
-		`[[:space:]]*$`)
-
-	// Regular expression to match escaped chararchters
-	unescape = regexp.MustCompile(`\\.`)
-
-	// Error to return if a message couldn't be parsed
-	errArgumentMismatch = errors.New("argument mismatch")
-)
-
-func descape(str string) string {
-	switch str[1] {
-	case 'n':
-		return "\n"
-	default:
-		return str[1:]
-	}
-}
-
-// parse destructs RAW and tries to assign the parts to PARAMS
-func parse(raw string, params ...interface{}) error {
-	var (
-		inquotes bool
-		escape   bool
-		err      error
-
-		i   int
-		arg string
-	)
-
-	for i, arg = range strings.FieldsFunc(raw, func(c rune) bool {
-		if inquotes {
-			if escape {
-				escape = false
-				return false
-			} else if c == '"' {
-				inquotes = false
-				return true
-			} else {
-				escape = c == '\\'
-				return false
-			}
-		} else {
-			inquotes = c == '"'
-			return unicode.IsSpace(c) || inquotes
-		}
-	}) {
-		if i >= len(params) {
-			return errArgumentMismatch
-		}
-
-		switch param := params[i].(type) {
-		case *string:
-			*param = unescape.ReplaceAllStringFunc(arg, descape)
-			*param = arg
-		case *uint64:
-			*param, err = strconv.ParseUint(arg, 10, 64)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if i+1 != len(params) {
-		return errArgumentMismatch
-	}
-
-	return nil
-}
-
 // Interpret parses and evaluates INPUT
 func (cli *client) interpret(input string) error {
 	dbg := cli.conf.Debug.Printf
 
-	input = strings.TrimSpace(input)
-	if input == "" { // Ignore empty lines
+	if strings.TrimSpace(input) == "" { // Ignore empty lines
 		return nil
 	}
 
-	matches := tokenizer.FindStringSubmatch(input)
-	if matches == nil {
-		dbg("Malformed input: %v", input)
+	cmd, err := msg.Lex(input)
+	if err != nil {
+		dbg("Malformed input from %s: %s", cli, err)
+		var id uint64
+		if cmd != nil {
+			id = cmd.Id
+		}
+		cli.error(id, err.Error())
 		return nil
 	}
 
 	var (
 		game    *kgp.Game
-		id, ref uint64
-		err     error
-
-		cmd  = matches[3]
-		args = matches[4]
+		id, ref = cmd.Id, cmd.Ref
 	)
-	if matches[1] != "" {
-		id, err = strconv.ParseUint(matches[1], 10, 64)
-		if err != nil {
-			return nil
-		}
-	}
-	if matches[2] != "" {
-		ref, err = strconv.ParseUint(matches[2], 10, 64)
-		if err != nil {
-			return nil
-		}
-	}
 
 	cli.glock.Lock()
 	game = cli.games[ref]
 	cli.glock.Unlock()
 
-	switch cmd {
+	switch cmd.Name {
 	case "mode":
 		if cli.init {
 			cli.error(id, "Duplicate \"mode\" request")
+			atomic.StoreUint32(&cli.bye, 1)
 			cli.kill()
 			return nil
 		}
 
 		var mode string
-		err = parse(args, &mode)
+		err = cmd.Scan(&mode)
 		if err != nil {
-			return err
+			cli.error(id, err.Error())
+			return nil
 		}
 
 		switch mode {
-		case "freeplay":
-			cli.conf.GM.Schedule(cli)
+		case "freeplay", "oware":
+			if mode == "oware" {
+				cli.game = kgp.OWARE
+			}
+			if !cli.configurable() {
+				cli.error(id, "Unsupported board configuration")
+				atomic.StoreUint32(&cli.bye, 1)
+				cli.kill()
+				return nil
+			}
+			cli.init = true
+
+			// If the client has been expected to resume a
+			// game, we don't have to schedule it.
+			if !cli.claim() {
+				cli.conf.GM.Schedule(cli)
+			}
 			cli.respond(id, "ok")
 		default:
-			cli.error(id, "Unsupported mode %q", mode)
+			cli.error(id, fmt.Sprintf("Unsupported mode %q", mode))
 		}
 	case "move":
 		if game == nil {
@@ -180,9 +104,10 @@ func (cli *client) interpret(input string) error {
 		}
 
 		var pit uint64
-		err = parse(args, &pit)
+		err = cmd.Scan(&pit)
 		if err != nil {
-			return err
+			cli.error(id, err.Error())
+			return nil
 		}
 
 		cli.resp <- &response{
@@ -219,44 +144,70 @@ func (cli *client) interpret(input string) error {
 		// intermediate representation. If we need to convert
 		// it to something else later on, we will do so then.
 		var key, val string
-		err := parse(args, &key, &val)
+		err := cmd.Scan(&key, &val)
 		if err != nil {
-			return err
+			cli.error(id, err.Error())
+			return nil
 		}
 
 		switch key {
 		case "info:name":
-			if cli.user == defaultUser {
-				cli.user = &kgp.User{Token: val}
+			if !cli.anonymous(val) {
+				return nil
 			}
 			cli.user.Name = val
 		case "info:authors", "info:author":
-			if cli.user == defaultUser {
-				cli.user = &kgp.User{Token: val}
+			if !cli.anonymous(val) {
+				return nil
 			}
 			cli.user.Author = val
 		case "info:description":
-			if cli.user == defaultUser {
-				cli.user = &kgp.User{Token: val}
+			if !cli.anonymous(val) {
+				return nil
 			}
 			cli.user.Descr = val
 		case "info:comment":
 			cli.comm = val
+		case "board:size", "board:init":
+			if cli.init {
+				cli.error(id, fmt.Sprintf("%q must be set before \"mode\"", key))
+				return nil
+			}
+			n, err := strconv.ParseUint(val, 10, 0)
+			if err != nil || n == 0 {
+				cli.error(id, fmt.Sprintf("Invalid value for %q", key))
+				return nil
+			}
+			if key == "board:size" {
+				cli.size = uint(n)
+			} else {
+				cli.stones = uint(n)
+			}
 		case "auth:token":
+			clock.Lock()
+			err := cli.authenticate(val)
+			clock.Unlock()
+			if err != nil {
+				cli.reject(err)
+				return nil
+			}
+
 			cli.user = &kgp.User{
 				Name:   cli.user.Name,
 				Author: cli.user.Author,
 				Descr:  cli.user.Descr,
 				Token:  val,
 			}
+			cli.authed = true
 			if cli.user.Descr == defaultUser.Descr {
 				cli.user.Descr = ""
 			}
 		}
 	case "goodbye":
+		atomic.StoreUint32(&cli.bye, 1)
 		cli.kill()
 	default:
-		dbg("Invalid command %q", input)
+		dbg("Invalid command %q", cmd.Name)
 	}
 
 	return nil
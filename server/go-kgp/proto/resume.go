@@ -0,0 +1,169 @@
+// Resuming games with reconnecting clients
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package proto
+
+import (
+	"sync"
+	"time"
+
+	"go-kgp"
+	"go-kgp/conf"
+)
+
+var (
+	// Clients without a connection that are waiting to be
+	// claimed, indexed by the token of the user.
+	waiting = make(map[string][]*client)
+	wlock   sync.Mutex
+)
+
+// Placeholder creates an agent for USER without a connection
+//
+// A client connecting with the same token as USER can claim the
+// placeholder, see Await.
+func Placeholder(conf *conf.Conf, user *kgp.User) kgp.Agent {
+	closed := make(chan struct{})
+	close(closed)
+	return &client{
+		conf:    conf,
+		user:    user,
+		games:   make(map[uint64]*kgp.Game),
+		closed:  closed,
+		claimed: make(chan struct{}),
+	}
+}
+
+// Await blocks until some client claims CLI
+//
+// If no client claims CLI until TIMEOUT has passed, nil is returned.
+// Clients without a token cannot be claimed.
+func (cli *client) Await(timeout time.Duration) kgp.Agent {
+	if cli.user.Token == "" {
+		return nil
+	}
+
+	cli.iolock.Lock()
+	cli.deadline = time.Now().Add(timeout)
+	cli.iolock.Unlock()
+
+	cli.wait()
+	if next := cli.successor(nil); next != nil {
+		return next
+	}
+	return nil
+}
+
+// Wait adds CLI to the list of clients that may be claimed
+//
+// CLI is removed from the list once its deadline has passed, even if
+// no game is waiting for it to be claimed.
+func (cli *client) wait() {
+	defer wlock.Unlock()
+	wlock.Lock()
+
+	token := cli.user.Token
+	waiting[token] = append(waiting[token], cli)
+	time.AfterFunc(time.Until(cli.expires()), func() {
+		defer wlock.Unlock()
+		wlock.Lock()
+		cli.unwait()
+	})
+}
+
+// Unwait removes CLI from the list of clients that may be claimed
+//
+// The return value indicates if CLI was still waiting.  The caller
+// must hold wlock.
+func (cli *client) unwait() bool {
+	token := cli.user.Token
+	for i, w := range waiting[token] {
+		if w == cli {
+			waiting[token] = append(waiting[token][:i],
+				waiting[token][i+1:]...)
+			if len(waiting[token]) == 0 {
+				delete(waiting, token)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Expires returns the deadline until which CLI may be claimed
+func (cli *client) expires() time.Time {
+	cli.iolock.Lock()
+	defer cli.iolock.Unlock()
+	return cli.deadline
+}
+
+// Successor returns the client that has claimed CLI
+//
+// If CLI has not been claimed yet, successor blocks until the
+// deadline has passed, and returns nil if CLI is still unclaimed.  If
+// TIMEOUT fires before, nil is returned as well, but CLI may still be
+// claimed later on.
+func (cli *client) successor(timeout <-chan time.Time) *client {
+	select {
+	case <-cli.claimed:
+		return cli.next
+	case <-timeout:
+		return nil
+	case <-time.After(time.Until(cli.expires())):
+	}
+
+	// Check if the client has been claimed in the meantime,
+	// otherwise remove it from the waiting list.
+	defer wlock.Unlock()
+	wlock.Lock()
+	if cli.unwait() {
+		return nil
+	}
+	return cli.next
+}
+
+// Claim takes over the first client waiting for the same token
+//
+// Only clients that authenticated using "auth:token" may claim
+// another client, as the token of an anonymous client is just the
+// name it chose.  Clients whose deadline has passed are discarded.
+func (cli *client) claim() bool {
+	token := cli.user.Token
+	if token == "" || !cli.authed {
+		return false
+	}
+
+	defer wlock.Unlock()
+	wlock.Lock()
+
+	now := time.Now()
+	for len(waiting[token]) > 0 {
+		w := waiting[token][0]
+		w.unwait()
+		if now.After(w.expires()) {
+			continue
+		}
+
+		cli.conf.Debug.Printf("%s claimed a waiting client", cli)
+		w.next = cli
+		close(w.claimed)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,406 @@
+// Resumption Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package proto
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-kgp"
+	"go-kgp/conf"
+	"go-kgp/msg"
+)
+
+// A game manager that forwards scheduled agents
+type scheduler struct {
+	conf.GameManager
+	agents chan kgp.Agent
+}
+
+func (s *scheduler) Schedule(a kgp.Agent) { s.agents <- a }
+func (s *scheduler) Unschedule(kgp.Agent) {}
+
+// Create a configuration for testing reconnecting clients
+func reconnecting(timeout time.Duration) (*conf.Conf, *scheduler) {
+	gm := &scheduler{agents: make(chan kgp.Agent, 1)}
+	c := *conf.Default(false)
+	c.Log = log.New(io.Discard, "", 0)
+	c.GM = gm
+	c.Ping = false
+	c.MaxConnToken = 0 // clients may reconnect before the old connection is closed
+	c.CommandRate = 0
+	c.MoveTimeout = time.Minute
+	c.ReconnectTimeout = timeout
+	return &c, gm
+}
+
+// The remote end of a connection to a client
+type peer struct {
+	conn net.Conn
+	cmds chan *msg.Command
+}
+
+// Connect to a new client
+func connect(t *testing.T, c *conf.Conf) *peer {
+	srv, conn := net.Pipe()
+	p := &peer{conn: conn, cmds: make(chan *msg.Command, 16)}
+	go func() {
+		defer close(p.cmds)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			cmd, err := msg.Lex(scanner.Text())
+			if err == nil {
+				p.cmds <- cmd
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	MakeClient(srv, c)
+	return p
+}
+
+// Send a command referring to REF
+func (p *peer) send(t *testing.T, ref uint64, name string, args ...interface{}) {
+	t.Helper()
+	_, err := fmt.Fprintf(p.conn, "%s\r\n", msg.Make(0, ref, name, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Skip commands until a command NAME has been received
+func (p *peer) expect(t *testing.T, name string) *msg.Command {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case cmd, ok := <-p.cmds:
+			if !ok {
+				t.Fatalf("Connection closed while waiting for %q", name)
+			}
+			if cmd.Name == name {
+				return cmd
+			}
+		case <-timeout:
+			t.Fatalf("Timed out while waiting for %q", name)
+		}
+	}
+}
+
+// Log in using TOKEN and request a game
+func (p *peer) login(t *testing.T, token string) {
+	t.Helper()
+	p.send(t, 0, "set", "auth:token", token)
+	p.send(t, 0, "mode", "freeplay")
+	p.expect(t, "ok")
+}
+
+// Wait for an agent to be scheduled
+func (s *scheduler) next(t *testing.T) kgp.Agent {
+	t.Helper()
+	select {
+	case a := <-s.agents:
+		return a
+	case <-time.After(5 * time.Second):
+		t.Fatal("No agent was scheduled")
+		return nil
+	}
+}
+
+// Generate a token that no other test uses
+//
+// Clients remain waiting after a test has finished, so tokens must
+// not be reused when a test is repeated.
+func unique(t *testing.T) string {
+	return fmt.Sprintf("%s-%d", t.Name(), atomic.AddUint64(&tokens, 1))
+}
+
+var tokens uint64
+
+// Return the number of clients waiting for TOKEN
+func waitingFor(token string) int {
+	wlock.Lock()
+	defer wlock.Unlock()
+	return len(waiting[token])
+}
+
+func TestPlaceholder(t *testing.T) {
+	c := conf.Default(false)
+
+	t.Run("claimed", func(t *testing.T) {
+		var (
+			user = &kgp.User{Token: unique(t)}
+			p    = Placeholder(c, user).(*client)
+			cli  = &client{conf: c, user: &kgp.User{Token: user.Token}, authed: true}
+			next = make(chan kgp.Agent)
+		)
+
+		go func() { next <- p.Await(time.Minute) }()
+		for !cli.claim() {
+			time.Sleep(time.Millisecond)
+		}
+		if a := <-next; a != cli {
+			t.Fatalf("Expected the placeholder to be claimed by %s, got %v", cli, a)
+		}
+		if n := waitingFor(user.Token); n != 0 {
+			t.Errorf("%d clients are still waiting", n)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		user := &kgp.User{Token: unique(t)}
+		p := Placeholder(c, user).(*client)
+		if a := p.Await(10 * time.Millisecond); a != nil {
+			t.Fatalf("Expected the placeholder to remain unclaimed, got %v", a)
+		}
+		if n := waitingFor(user.Token); n != 0 {
+			t.Errorf("%d clients are still waiting", n)
+		}
+		cli := &client{conf: c, user: &kgp.User{Token: user.Token}, authed: true}
+		if cli.claim() {
+			t.Error("A client claimed an expired placeholder")
+		}
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		p := Placeholder(c, &kgp.User{}).(*client)
+		if a := p.Await(time.Minute); a != nil {
+			t.Fatalf("Expected an anonymous placeholder to be unclaimable, got %v", a)
+		}
+	})
+}
+
+func TestReconnectIdle(t *testing.T) {
+	c, gm := reconnecting(20 * time.Millisecond)
+	token := unique(t)
+
+	first := connect(t, c)
+	first.login(t, token)
+	old := gm.next(t)
+
+	// The client drops while it is waiting for a game, and does
+	// not reconnect in time.
+	first.conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	if old.Alive() {
+		t.Error("The dropped client is still alive")
+	}
+	if n := waitingFor(token); n != 0 {
+		t.Errorf("%d clients are still waiting", n)
+	}
+
+	// A new client using the same token has to be scheduled
+	second := connect(t, c)
+	second.login(t, token)
+	select {
+	case a := <-gm.agents:
+		if a == old {
+			t.Error("The dropped client was scheduled again")
+		}
+	default:
+		t.Fatal("The new client was not scheduled")
+	}
+}
+
+func TestReconnectExpired(t *testing.T) {
+	c, gm := reconnecting(time.Minute)
+	token := unique(t)
+
+	// A client whose deadline has passed, but is still waiting
+	stale := &client{
+		conf:     c,
+		user:     &kgp.User{Token: token},
+		claimed:  make(chan struct{}),
+		deadline: time.Now().Add(-time.Second),
+	}
+	wlock.Lock()
+	waiting[token] = append(waiting[token], stale)
+	wlock.Unlock()
+
+	p := connect(t, c)
+	p.login(t, token)
+	select {
+	case <-gm.agents:
+	default:
+		t.Fatal("The client claimed an expired client instead of being scheduled")
+	}
+	if n := waitingFor(token); n != 0 {
+		t.Errorf("%d clients are still waiting", n)
+	}
+	if stale.next != nil {
+		t.Error("The expired client was claimed")
+	}
+}
+
+func TestReconnectAnonymous(t *testing.T) {
+	c, gm := reconnecting(time.Minute)
+	token := unique(t)
+
+	victim := Placeholder(c, &kgp.User{Token: token})
+	claimed := make(chan kgp.Agent)
+	go func() { claimed <- victim.(*client).Await(100 * time.Millisecond) }()
+	for waitingFor(token) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// An anonymous client is identified by its name, which must
+	// not allow it to take over the game of another client.
+	p := connect(t, c)
+	p.send(t, 0, "set", "info:name", token)
+	p.send(t, 0, "mode", "freeplay")
+	p.expect(t, "ok")
+	gm.next(t)
+	if a := <-claimed; a != nil {
+		t.Error("An anonymous client claimed a waiting client")
+	}
+}
+
+func TestReconnectMove(t *testing.T) {
+	c, gm := reconnecting(time.Minute)
+	token := unique(t)
+
+	first := connect(t, c)
+	first.login(t, token)
+	south := gm.next(t)
+
+	var (
+		board = kgp.MakeBoard(3, 3)
+		game  = &kgp.Game{
+			Id:    7,
+			Board: board,
+			South: south,
+			North: Placeholder(c, &kgp.User{}),
+		}
+		moves = make(chan *kgp.Move, 1)
+	)
+	go func() {
+		m, _ := south.Request(game)
+		moves <- m
+	}()
+
+	// The connection drops while the client is thinking
+	first.expect(t, "state")
+	first.conn.Close()
+	for waitingFor(token) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The client reconnects, and has to be informed about the
+	// game it is playing before the request is repeated.
+	second := connect(t, c)
+	second.login(t, token)
+	set := second.expect(t, "set")
+	var key, val string
+	if err := set.Scan(&key, &val); err != nil {
+		t.Fatal(err)
+	}
+	if key != "game:id" || val != "7" {
+		t.Errorf("Expected the game ID to be set, got %s = %s", key, val)
+	}
+	state := second.expect(t, "state")
+	var repr string
+	if err := state.Scan(&repr); err != nil {
+		t.Fatal(err)
+	}
+	if repr != board.String() {
+		t.Errorf("Expected the state %s, got %s", board, repr)
+	}
+	// Responses are only matched to a request once the request
+	// has been registered, which happens after the state is sent.
+	time.Sleep(10 * time.Millisecond)
+	second.send(t, state.Id, "move", 2)
+	second.send(t, state.Id, "yield")
+
+	select {
+	case m := <-moves:
+		if m.Choice != 1 || m.Source != kgp.CHOSEN {
+			t.Errorf("Expected the chosen move 1, got %d (%s)", m.Choice, m.Source)
+		}
+		if m.Agent != south {
+			t.Error("The move was not made by the original agent")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No move was made")
+	}
+	select {
+	case <-gm.agents:
+		t.Error("The reconnected client was scheduled")
+	default:
+	}
+}
+
+func TestReconnectDeadline(t *testing.T) {
+	c, gm := reconnecting(time.Minute)
+	c.MoveTimeout = 50 * time.Millisecond
+	token := unique(t)
+
+	p := connect(t, c)
+	p.login(t, token)
+	south := gm.next(t)
+
+	game := &kgp.Game{
+		Id:    7,
+		Board: kgp.MakeBoard(3, 3),
+		South: south,
+		North: Placeholder(c, &kgp.User{}),
+	}
+	moves := make(chan *kgp.Move, 1)
+	go func() {
+		m, _ := south.Request(game)
+		moves <- m
+	}()
+
+	// The connection drops while the client is thinking, and the
+	// client does not reconnect before the move is due
+	p.expect(t, "state")
+	p.conn.Close()
+
+	select {
+	case m := <-moves:
+		if m == nil || m.Source != kgp.FALLBACK {
+			t.Errorf("Expected a fallback move, got %v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The request waited for the client to reconnect")
+	}
+	if !south.Alive() {
+		t.Error("The client may no longer reconnect")
+	}
+
+	// Further requests do not wait for the client either
+	go func() {
+		m, _ := south.Request(game)
+		moves <- m
+	}()
+	select {
+	case m := <-moves:
+		if m == nil || m.Source != kgp.FALLBACK {
+			t.Errorf("Expected a fallback move, got %v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The request waited for the client to reconnect")
+	}
+}
@@ -0,0 +1,93 @@
+// Encrypted connections
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package proto
+
+import (
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"go-kgp/conf"
+)
+
+// Certificate used by the TLS listener
+//
+// The certificate is re-read from disk whenever the server receives
+// a SIGHUP, so that it can be renewed without restarting the server.
+type certificate struct {
+	conf *conf.Conf
+	lock sync.RWMutex
+	cert *tls.Certificate
+	hup  chan os.Signal
+}
+
+// Load the configured certificate and start watching for SIGHUP
+func loadCertificate(conf *conf.Conf) (*certificate, error) {
+	c := &certificate{
+		conf: conf,
+		hup:  make(chan os.Signal, 1),
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	signal.Notify(c.hup, syscall.SIGHUP)
+	go func() {
+		for range c.hup {
+			c.conf.Log.Print("Reloading TLS certificate")
+			if err := c.reload(); err != nil {
+				c.conf.Log.Print(err)
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+// Read the certificate and key from disk
+//
+// If the files cannot be loaded, the previous certificate remains
+// in use.
+func (c *certificate) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.conf.TLSCert, c.conf.TLSKey)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.cert = &cert
+	c.lock.Unlock()
+	return nil
+}
+
+// Return the current certificate (see tls.Config.GetCertificate)
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	defer c.lock.RUnlock()
+	c.lock.RLock()
+	return c.cert, nil
+}
+
+// Stop watching for SIGHUP
+func (c *certificate) stop() {
+	signal.Stop(c.hup)
+	close(c.hup)
+}
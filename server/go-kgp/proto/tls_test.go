@@ -0,0 +1,165 @@
+// TLS Listener Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package proto
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-kgp/conf"
+)
+
+// Write a self-signed certificate for localhost into DIR
+func selfSigned(t *testing.T, dir string, serial int64) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, block := range map[string]*pem.Block{
+		"cert.pem": {Type: "CERTIFICATE", Bytes: der},
+		"key.pem":  {Type: "EC PRIVATE KEY", Bytes: priv},
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return cert
+}
+
+// Connect to ADDR and check that the server presents CERT
+func expectCertificate(t *testing.T, addr string, cert *x509.Certificate) {
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:    roots,
+		ServerName: "localhost",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	peer := conn.ConnectionState().PeerCertificates
+	if len(peer) == 0 || peer[0].SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Fatalf("Expected certificate %s", cert.SerialNumber)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "kgp 1 0 0\r\n" {
+		t.Fatalf("Unexpected greeting %q", line)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	first := selfSigned(t, dir, 1)
+
+	config := conf.Default(false)
+	config.TLSCert = filepath.Join(dir, "cert.pem")
+	config.TLSKey = filepath.Join(dir, "key.pem")
+
+	cert, err := loadCertificate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cert.stop()
+
+	man := &tcp{conf: config, cert: cert}
+	l, err := man.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("kgp 1 0 0\r\n"))
+			conn.Close()
+		}
+	}()
+
+	addr := l.Addr().String()
+	expectCertificate(t, addr, first)
+
+	// Replace the certificate on disk and reload it
+	second := selfSigned(t, dir, 2)
+	if err := cert.reload(); err != nil {
+		t.Fatal(err)
+	}
+	expectCertificate(t, addr, second)
+
+	// A broken certificate should not replace the current one
+	err = os.WriteFile(config.TLSCert, []byte("garbage"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.reload(); err == nil {
+		t.Fatal("Expected loading an invalid certificate to fail")
+	}
+	expectCertificate(t, addr, second)
+}
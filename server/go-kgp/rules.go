@@ -0,0 +1,151 @@
+// Kalah Rule Variants
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp . If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	Capture   uint8
+	Remainder uint8
+)
+
+// When the last stone lands in an empty pit of the player
+const (
+	// Capture if the opposite pit is not empty
+	CAPTURE_OPPOSITE Capture = iota
+	// Always capture, even if the opposite pit is empty
+	CAPTURE_ALWAYS
+	// Never capture
+	CAPTURE_NONE
+)
+
+// Who receives the remaining stones when one side is empty
+const (
+	// Every player collects the stones on their side
+	REMAINDER_OWNER Remainder = iota
+	// The player who emptied their side collects all stones
+	REMAINDER_EMPTIER
+)
+
+// Rules of a Kalah game
+//
+// The zero value describes the default rules.
+type Rules struct {
+	Capture   Capture
+	Remainder Remainder
+	// Should the game end as soon as a store holds more than
+	// half of all stones?
+	NoEarlyWin bool
+}
+
+// The rules used unless specified otherwise
+var DefaultRules Rules
+
+func (c Capture) String() string {
+	switch c {
+	case CAPTURE_OPPOSITE:
+		return "opposite"
+	case CAPTURE_ALWAYS:
+		return "always"
+	case CAPTURE_NONE:
+		return "none"
+	default:
+		panic(fmt.Sprintf("Illegal capture rule: %d", c))
+	}
+}
+
+func (r Remainder) String() string {
+	switch r {
+	case REMAINDER_OWNER:
+		return "owner"
+	case REMAINDER_EMPTIER:
+		return "emptier"
+	default:
+		panic(fmt.Sprintf("Illegal remainder rule: %d", r))
+	}
+}
+
+// String returns a textual representation of the rules
+//
+// The representation is a space separated list of key-value pairs,
+// e.g. "capture=opposite remainder=owner earlywin=yes", that can be
+// parsed by ParseRules.
+func (r Rules) String() string {
+	earlywin := "yes"
+	if r.NoEarlyWin {
+		earlywin = "no"
+	}
+	return fmt.Sprintf("capture=%s remainder=%s earlywin=%s",
+		r.Capture, r.Remainder, earlywin)
+}
+
+// ParseRules parses the textual representation of rules
+//
+// Rules that are not mentioned take their default value.
+func ParseRules(spec string) (Rules, error) {
+	var r Rules
+
+	for _, field := range strings.Fields(spec) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return r, fmt.Errorf("Invalid rule %q", field)
+		}
+
+		key, val := parts[0], parts[1]
+		switch key {
+		case "capture":
+			switch val {
+			case "opposite":
+				r.Capture = CAPTURE_OPPOSITE
+			case "always":
+				r.Capture = CAPTURE_ALWAYS
+			case "none":
+				r.Capture = CAPTURE_NONE
+			default:
+				return r, fmt.Errorf("Unknown capture rule %q", val)
+			}
+		case "remainder":
+			switch val {
+			case "owner":
+				r.Remainder = REMAINDER_OWNER
+			case "emptier":
+				r.Remainder = REMAINDER_EMPTIER
+			default:
+				return r, fmt.Errorf("Unknown remainder rule %q", val)
+			}
+		case "earlywin":
+			switch val {
+			case "yes":
+				r.NoEarlyWin = false
+			case "no":
+				r.NoEarlyWin = true
+			default:
+				return r, fmt.Errorf("Invalid value %q for earlywin", val)
+			}
+		default:
+			return r, fmt.Errorf("Unknown rule %q", key)
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,282 @@
+// Kalah Rule Variant Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp . If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSowRules(t *testing.T) {
+	for i, test := range []struct {
+		rules      Rules
+		start, end *Board
+		move       uint
+		again      bool
+	}{
+		{ // capture a non-empty pit
+			rules: Rules{Capture: CAPTURE_OPPOSITE},
+			start: &Board{
+				northPits: []uint{2, 3, 4},
+				southPits: []uint{1, 0, 2},
+			},
+			end: &Board{
+				south:     4,
+				northPits: []uint{2, 0, 4},
+				southPits: []uint{0, 0, 2},
+			},
+		}, { // do not capture an empty pit
+			rules: Rules{Capture: CAPTURE_OPPOSITE},
+			start: &Board{
+				northPits: []uint{2, 0, 4},
+				southPits: []uint{1, 0, 2},
+			},
+			end: &Board{
+				northPits: []uint{2, 0, 4},
+				southPits: []uint{0, 1, 2},
+			},
+		}, {
+			rules: Rules{Capture: CAPTURE_ALWAYS},
+			start: &Board{
+				northPits: []uint{2, 3, 4},
+				southPits: []uint{1, 0, 2},
+			},
+			end: &Board{
+				south:     4,
+				northPits: []uint{2, 0, 4},
+				southPits: []uint{0, 0, 2},
+			},
+		}, { // capture the last stone, even if the pit is empty
+			rules: Rules{Capture: CAPTURE_ALWAYS},
+			start: &Board{
+				northPits: []uint{2, 0, 4},
+				southPits: []uint{1, 0, 2},
+			},
+			end: &Board{
+				south:     1,
+				northPits: []uint{2, 0, 4},
+				southPits: []uint{0, 0, 2},
+			},
+		}, {
+			rules: Rules{Capture: CAPTURE_NONE},
+			start: &Board{
+				northPits: []uint{2, 3, 4},
+				southPits: []uint{1, 0, 2},
+			},
+			end: &Board{
+				northPits: []uint{2, 3, 4},
+				southPits: []uint{0, 1, 2},
+			},
+		}, { // every side collects their own stones
+			rules: Rules{Remainder: REMAINDER_OWNER},
+			start: &Board{
+				northPits: []uint{1, 2, 3},
+				southPits: []uint{0, 0, 1},
+			},
+			end: &Board{
+				north:     6,
+				south:     1,
+				northPits: []uint{0, 0, 0},
+				southPits: []uint{0, 0, 0},
+			},
+			move:  2,
+			again: true,
+		}, { // south emptied their side and collects all stones
+			rules: Rules{Remainder: REMAINDER_EMPTIER},
+			start: &Board{
+				northPits: []uint{1, 2, 3},
+				southPits: []uint{0, 0, 1},
+			},
+			end: &Board{
+				north:     0,
+				south:     7,
+				northPits: []uint{0, 0, 0},
+				southPits: []uint{0, 0, 0},
+			},
+			move:  2,
+			again: true,
+		}, { // the game ends early
+			rules: Rules{},
+			start: &Board{
+				south:     5,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{0, 1, 1},
+			},
+			end: &Board{
+				north:     3,
+				south:     7,
+				northPits: []uint{0, 0, 0},
+				southPits: []uint{0, 0, 0},
+			},
+			move:  2,
+			again: true,
+		}, { // the game continues, even though south must win
+			rules: Rules{NoEarlyWin: true},
+			start: &Board{
+				south:     5,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{0, 1, 1},
+			},
+			end: &Board{
+				south:     6,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{0, 1, 0},
+			},
+			move:  2,
+			again: true,
+		},
+	} {
+		test.start.SetRules(test.rules)
+		test.end.SetRules(test.rules)
+		mirror := test.start.Mirror().Copy()
+
+		again := test.start.Sow(South, test.move)
+		if test.again != again {
+			t.Errorf("(%d) Didn't recognize repeat move", i)
+		} else if !reflect.DeepEqual(test.start, test.end) {
+			t.Errorf("(%d) Expected %s, got %s", i, test.end, test.start)
+		}
+
+		// The rules must apply to both sides in the same way
+		mirror.Sow(North, test.move)
+		if !reflect.DeepEqual(mirror, test.end.Mirror()) {
+			t.Errorf("(%d) Expected %s, got %s for north",
+				i, test.end.Mirror(), mirror)
+		}
+	}
+}
+
+func TestOutcomeRules(t *testing.T) {
+	for i, test := range []struct {
+		rules   Rules
+		board   *Board
+		over    bool
+		outcome Outcome
+	}{
+		{
+			rules: Rules{Remainder: REMAINDER_OWNER},
+			board: &Board{
+				north:     3,
+				south:     2,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{0, 0, 0},
+			},
+			over:    true,
+			outcome: LOSS,
+		}, {
+			rules: Rules{Remainder: REMAINDER_EMPTIER},
+			board: &Board{
+				north:     3,
+				south:     2,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{0, 0, 0},
+			},
+			over:    true,
+			outcome: WIN,
+		}, {
+			// With an early win, the remaining stones are
+			// collected by their owners
+			rules: Rules{Remainder: REMAINDER_EMPTIER},
+			board: &Board{
+				north:     1,
+				south:     7,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{1, 0, 0},
+			},
+			over:    true,
+			outcome: WIN,
+		}, {
+			rules: Rules{NoEarlyWin: true},
+			board: &Board{
+				north:     1,
+				south:     7,
+				northPits: []uint{1, 1, 1},
+				southPits: []uint{1, 0, 0},
+			},
+			over: false,
+		},
+	} {
+		test.board.SetRules(test.rules)
+		if over := test.board.Over(); over != test.over {
+			t.Errorf("(%d) Expected over to be %t", i, test.over)
+			continue
+		}
+		if !test.over {
+			continue
+		}
+
+		before := test.board.String()
+		outcome := test.board.Outcome(South)
+		if outcome != test.outcome {
+			t.Errorf("(%d) Expected %d, got %d", i, test.outcome, outcome)
+		}
+		if test.board.String() != before {
+			t.Errorf("(%d) Outcome modified the board", i)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	for i, test := range []struct {
+		spec  string
+		rules Rules
+		fail  bool
+	}{
+		{spec: "", rules: DefaultRules},
+		{spec: DefaultRules.String(), rules: DefaultRules},
+		{spec: "capture=always", rules: Rules{Capture: CAPTURE_ALWAYS}},
+		{spec: "capture=none", rules: Rules{Capture: CAPTURE_NONE}},
+		{spec: "remainder=emptier", rules: Rules{Remainder: REMAINDER_EMPTIER}},
+		{spec: "earlywin=no", rules: Rules{NoEarlyWin: true}},
+		{
+			spec: " earlywin=no  capture=always remainder=emptier ",
+			rules: Rules{
+				Capture:    CAPTURE_ALWAYS,
+				Remainder:  REMAINDER_EMPTIER,
+				NoEarlyWin: true,
+			},
+		},
+		{spec: "capture", fail: true},
+		{spec: "capture=sometimes", fail: true},
+		{spec: "remainder=nobody", fail: true},
+		{spec: "earlywin=maybe", fail: true},
+		{spec: "seeds=6", fail: true},
+	} {
+		rules, err := ParseRules(test.spec)
+		if test.fail {
+			if err == nil {
+				t.Errorf("(%d) Expected %q to fail", i, test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("(%d) Unexpected error: %s", i, err)
+		} else if rules != test.rules {
+			t.Errorf("(%d) Expected %s, got %s", i, test.rules, rules)
+		}
+
+		// The textual representation must be parsed back
+		// into the same rules
+		again, err := ParseRules(rules.String())
+		if err != nil || again != rules {
+			t.Errorf("(%d) Could not parse %q", i, rules.String())
+		}
+	}
+}
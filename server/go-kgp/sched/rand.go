@@ -20,23 +20,29 @@
 package sched
 
 import (
+	"context"
 	random "math/rand"
+	"sync"
 	"time"
 
 	"go-kgp"
 	"go-kgp/bot"
 	"go-kgp/conf"
 	"go-kgp/game"
+	"go-kgp/proto"
 )
 
-// The intent is not to have a secure source of random values, but
-// just to avoid a predictive shuffling of north/south positions.
-func init() { random.Seed(time.Now().UnixMicro()) }
-
 type rand struct {
 	conf *conf.Conf
 	add  chan kgp.Agent
 	rem  chan kgp.Agent
+
+	// Shutdown management
+	quit    chan struct{}      // closed to stop scheduling
+	stopped chan struct{}      // closed when scheduling has stopped
+	ctx     context.Context    // cancelled to interrupt all games
+	cancel  context.CancelFunc // cancels ctx
+	games   sync.WaitGroup     // running games
 }
 
 type Bot interface {
@@ -49,16 +55,116 @@ func isBot(a kgp.Agent) bool {
 	return ok
 }
 
+// An agent that wants to play a specific game
+//
+// Agents that do not implement this interface play Kalah.
+type Player interface {
+	kgp.Agent
+	Mancala() kgp.Mancala
+}
+
+func mancala(a kgp.Agent) kgp.Mancala {
+	if p, ok := a.(Player); ok {
+		return p.Mancala()
+	}
+	return kgp.KALAH
+}
+
+// An agent that wants to play on a specific board
+//
+// A size or number of stones of zero indicates no preference.
+type Configured interface {
+	kgp.Agent
+	Configuration() (size, init uint)
+}
+
+// Combine the board preferences of A and B
+//
+// If the preferences are incompatible, false is returned.
+func configuration(a, b kgp.Agent) (size, init uint, ok bool) {
+	merge := func(x, y uint) (uint, bool) {
+		if x == 0 || x == y {
+			return y, true
+		}
+		return x, y == 0
+	}
+
+	var as, ai, bs, bi uint
+	if c, ok := a.(Configured); ok {
+		as, ai = c.Configuration()
+	}
+	if c, ok := b.(Configured); ok {
+		bs, bi = c.Configuration()
+	}
+	size, sok := merge(as, bs)
+	init, iok := merge(ai, bi)
+	return size, init, sok && iok
+}
+
+// Start a game in a new goroutine
+func (f *rand) play(g *kgp.Game, resume bool) {
+	f.games.Add(1)
+	go func() {
+		defer f.games.Done()
+		if resume {
+			game.Resume(f.ctx, g, f.conf)
+		} else {
+			game.Play(f.ctx, g, f.conf)
+		}
+	}()
+}
+
+// Start a duplicate match on a balanced opening in a new goroutine
+//
+// The opening is generated using a source of randomness created from
+// SEED.
+func (f *rand) match(board *kgp.Board, a, b kgp.Agent, seed int64) {
+	f.games.Add(1)
+	go func() {
+		defer f.games.Done()
+		o := bot.Opening(board,
+			f.conf.OpeningPlies,
+			f.conf.OpeningDepth,
+			f.conf.OpeningMargin,
+			random.New(random.NewSource(seed)))
+		f.conf.Debug.Printf("Generated opening %s (%d) for %s and %s",
+			o.Board, o.Eval, a, b)
+		game.Duplicate(f.ctx, o, a, b, seed, f.conf)
+	}()
+}
+
 func (f *rand) Start() {
 	var q []kgp.Agent
+	defer close(f.stopped)
 
-	for d, n := range f.conf.BotTypes {
-		for i := uint(0); i < n; i++ {
-			f.conf.Debug.Printf("Add MinMax bot with depth %d", d)
-			q = append(q, bot.MakeMinMax(d))
+	// The bots are added in a fixed order, so that the pairings
+	// only depend on the order in which agents connect
+	for _, m := range []kgp.Mancala{kgp.KALAH, kgp.OWARE} {
+		for _, b := range f.conf.Bots {
+			for i := uint(0); i < b.Count; i++ {
+				a, err := b.Make(m)
+				if err != nil {
+					f.conf.Log.Printf("Cannot add the bot %s: %s", b, err)
+					break
+				}
+				f.conf.Debug.Printf("Add bot %s for %s", b, m)
+				q = append(q, a)
+			}
 		}
 	}
 
+	// All pairings draw their seed from a source created from the
+	// configured seed, which is logged so that the decisions of
+	// the scheduler can be reproduced
+	rng := f.conf.Random()
+	f.conf.Log.Printf("Scheduling games using the random seed %d",
+		f.conf.Seed)
+
+	// Continue games that were interrupted by a restart
+	if f.conf.ResumeTimeout > 0 {
+		q = f.resume(q)
+	}
+
 	// Idea: FIFO but get lucky and you might be pulled ahead
 	//
 	// Priority: Reduce average waiting time
@@ -66,6 +172,9 @@ func (f *rand) Start() {
 	// Non-Priority: Avoid frequent encounters
 	for {
 		select {
+		case <-f.quit:
+			f.conf.Debug.Println("Stopped scheduling")
+			return
 		case a := <-f.add:
 			f.conf.Debug.Println("Schedule", a)
 			q = append(q, a)
@@ -93,23 +202,24 @@ func (f *rand) Start() {
 		}
 		q = q[:i]
 
-		// Try and select two agents, where at least one is
-		// not a bot
+		// Try and select two agents that want to play the
+		// same game, where at least one is not a bot
 		var (
 			north, south kgp.Agent
 			ni, si       int = -1, -1
 		)
 		for i, a := range q {
-			if !isBot(a) {
-				north = a
-				ni = i
-				break
+			if isBot(a) {
+				continue
 			}
-		}
-		for i, a := range q {
-			if i != ni {
-				south = a
-				si = i
+			for j, b := range q {
+				if j != i && f.compatible(a, b) {
+					north, south = a, b
+					ni, si = i, j
+					break
+				}
+			}
+			if south != nil {
 				break
 			}
 		}
@@ -119,37 +229,146 @@ func (f *rand) Start() {
 		if north == nil || south == nil {
 			continue
 		}
-		q[ni] = q[len(q)-1]
-		q[si] = q[len(q)-2]
-		q = q[:len(q)-2]
+		i = 0
+		for j, a := range q {
+			if j != ni && j != si {
+				q[i] = a
+				i++
+			}
+		}
+		q = q[:i]
+
+		// All random decisions for the pairing are drawn from a
+		// source created from a seed, that is stored with the
+		// game, so that they can be reproduced.
+		seed := rng.Int63()
+		prng := random.New(random.NewSource(seed))
 
 		// Start a game, but shuffle the order to avoid an
 		// advantage for bots or non-bots.
-		if random.Intn(2) == 0 {
+		if prng.Intn(2) == 0 {
 			north, south = south, north
 		}
+		board := f.board(north, south, prng)
+
+		if f.conf.Mode == conf.MODE_DUPLICATE {
+			f.match(board, north, south, seed)
+		} else {
+			f.play(&kgp.Game{
+				Board: board,
+				South: north,
+				North: south,
+				Seed:  seed,
+			}, false)
+		}
+	}
+}
+
+// Check if A and B can agree on a game to play
+func (f *rand) compatible(a, b kgp.Agent) bool {
+	if mancala(a) != mancala(b) {
+		return false
+	}
+	size, init, ok := configuration(a, b)
+	return ok && (mancala(a) == kgp.OWARE || f.conf.Supports(size, init))
+}
+
+// Create a board for a game between two compatible agents A and B
+//
+// The board configuration is drawn from RNG.
+func (f *rand) board(a, b kgp.Agent, rng *random.Rand) *kgp.Board {
+	if mancala(a) == kgp.OWARE {
+		return kgp.MakeOware()
+	}
+
+	size, init, _ := configuration(a, b)
+	c, ok := f.conf.Choose(size, init, rng)
+	if !ok {
+		panic("Incompatible agents were paired")
+	}
+	board := kgp.MakeBoard(c.Size, c.Init)
+	board.SetRules(f.conf.BoardRules)
+	return board
+}
+
+// Resume all ongoing games in the database
+//
+// Bots that participated in a game are taken from the queue Q, all
+// other agents have to reconnect.
+func (f *rand) resume(q []kgp.Agent) []kgp.Agent {
+	claim := func(a kgp.Agent, m kgp.Mancala) kgp.Agent {
+		for i, b := range q {
+			if isBot(b) && b.User().Token == a.User().Token && mancala(b) == m {
+				q[i] = q[len(q)-1]
+				q = q[:len(q)-1]
+				return b
+			}
+		}
+		return proto.Placeholder(f.conf, a.User())
+	}
+
+	c := make(chan *kgp.Game)
+	go f.conf.DB.QueryOngoing(context.Background(), c)
+	for g := range c {
+		g.North = claim(g.North, g.Board.Mancala())
+		g.South = claim(g.South, g.Board.Mancala())
+		f.conf.Debug.Printf("Attempting to resume game %d", g.Id)
+		f.play(g, true)
+	}
+
+	return q
+}
+
+func (f *rand) Schedule(a kgp.Agent) {
+	select {
+	case f.add <- a:
+	case <-f.quit:
+	}
+}
+
+func (f *rand) Unschedule(a kgp.Agent) {
+	select {
+	case f.rem <- a:
+	case <-f.quit:
+	}
+}
+
+// Shutdown stops scheduling and waits for all games to finish
+//
+// Games that do not finish within the drain timeout are interrupted
+// and aborted.
+func (f *rand) Shutdown() {
+	close(f.quit)
+	<-f.stopped
+
+	done := make(chan struct{})
+	go func() {
+		f.games.Wait()
+		close(done)
+	}()
 
-		go game.Play(&kgp.Game{
-			Board: kgp.MakeBoard(
-				f.conf.BoardSize,
-				f.conf.BoardInit),
-			South: north,
-			North: south,
-		}, f.conf)
+	select {
+	case <-done:
+		return
+	case <-time.After(f.conf.DrainTimeout):
+		f.conf.Log.Print("Interrupting all remaining games")
+		f.cancel()
 	}
-	panic("Quitting Random Scheduler")
+	<-done
 }
 
-func (f *rand) Schedule(a kgp.Agent)   { f.add <- a }
-func (f *rand) Unschedule(a kgp.Agent) { f.rem <- a }
-func (*rand) Shutdown()                {}
-func (*rand) String() string           { return "Random Scheduler" }
+func (*rand) String() string { return "Random Scheduler" }
 
 func MakeRandom(config *conf.Conf) conf.GameManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	var man conf.GameManager = &rand{
-		add:  make(chan kgp.Agent, 1),
-		rem:  make(chan kgp.Agent, 1),
-		conf: config,
+		add:     make(chan kgp.Agent, 1),
+		rem:     make(chan kgp.Agent, 1),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		conf:    config,
 	}
 	return man
 }
@@ -0,0 +1,85 @@
+// Board Validation
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import "fmt"
+
+// Validate checks if the board can be the result of a game that
+// started with SIZE pits on each side, each holding INIT stones
+//
+// Stones must neither be created nor lost.  As the pits hold unsigned
+// numbers, a pit that holds more stones than there are on the board
+// is reported as having underflowed.  Beyond that, only states that no
+// game can reach by sowing are detected (e.g. a finished game whose
+// remaining stones were not collected), so a valid board is not
+// necessarily reachable.
+func (b *Board) Validate(size, init uint) error {
+	if uint(len(b.northPits)) != size || uint(len(b.southPits)) != size {
+		return fmt.Errorf("expected %d pits on each side, found %d and %d",
+			size, len(b.northPits), len(b.southPits))
+	}
+	if b.mancala == OWARE && (size != OWARE_SIZE || init != OWARE_INIT) {
+		return fmt.Errorf("oware is not played with %d pits of %d seeds",
+			size, init)
+	}
+
+	var (
+		total  = 2 * size * init
+		stones uint
+	)
+	count := func(n uint, what string, side Side) error {
+		if n > total {
+			return fmt.Errorf("the %s of %s holds %d stones, but there are only %d",
+				what, side, n, total)
+		}
+		stones += n
+		return nil
+	}
+	for _, side := range []Side{North, South} {
+		if err := count(b.Store(side), "store", side); err != nil {
+			return err
+		}
+		for i := uint(0); i < size; i++ {
+			what := fmt.Sprintf("pit %d", i+1)
+			if err := count(b.Pit(side, i), what, side); err != nil {
+				return err
+			}
+		}
+	}
+	if stones != total {
+		return fmt.Errorf("expected %d stones, found %d", total, stones)
+	}
+
+	if b.mancala == OWARE && b.idle > OWARE_IDLE {
+		return fmt.Errorf("%d moves without a capture exceed the limit of %d",
+			b.idle, OWARE_IDLE)
+	}
+
+	// Sowing collects the remaining stones when a game is over
+	if b.Over() {
+		north, south := b.remaining()
+		if north > 0 || south > 0 {
+			return fmt.Errorf("the game is over, but %d stones were not collected",
+				north+south)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+// Board Validation Tests
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package kgp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for i, test := range []struct {
+		board      string
+		size, init uint
+		mancala    Mancala
+		valid      bool
+	}{
+		{board: "<3,0,0,3,3,3,3,3,3>", size: 3, init: 3, valid: true},
+		{board: "<3,4,1,0,3,3,3,0,4>", size: 3, init: 3, valid: true},
+		{board: "<3,10,8,0,0,0,0,0,0>", size: 3, init: 3, valid: true},
+		{board: "<3,0,0,3,3,3,3,3,3>", size: 3, init: 4, valid: false},
+		{board: "<3,0,0,3,3,3,3,3,3>", size: 4, init: 3, valid: false},
+		{board: "<3,1,0,3,3,3,3,3,3>", size: 3, init: 3, valid: false},
+		{board: "<3,0,0,3,3,3,3,3,2>", size: 3, init: 3, valid: false},
+		// The game is over, but not all stones were collected
+		{board: "<3,4,0,0,0,0,3,4,7>", size: 3, init: 3, valid: false},
+		{board: "<3,10,0,1,1,1,1,1,3>", size: 3, init: 3, valid: false},
+		{board: "<6,0,0,4,4,4,4,4,4,4,4,4,4,4,4>", size: 6, init: 4,
+			mancala: OWARE, valid: true},
+		{board: "<3,0,0,3,3,3,3,3,3>", size: 3, init: 3,
+			mancala: OWARE, valid: false},
+	} {
+		b, err := Parse(test.board)
+		if err != nil {
+			t.Fatalf("(%d) Failed to parse %s: %s", i, test.board, err)
+		}
+		b.SetMancala(test.mancala)
+		err = b.Validate(test.size, test.init)
+		if test.valid && err != nil {
+			t.Errorf("(%d) Expected %s to be valid: %s", i, b, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("(%d) Expected %s to be invalid", i, b)
+		}
+	}
+
+	// A pit that underflowed holds more stones than the board
+	b := MakeBoard(3, 3)
+	b.northPits[0] = math.MaxUint
+	b.southPits[0] = 4
+	if b.Validate(3, 3) == nil {
+		t.Errorf("Expected the underflow in %s to be detected", b)
+	}
+
+	// Repeated capture counters only occur on Oware boards
+	o := MakeOware()
+	o.idle = OWARE_IDLE + 1
+	if o.Validate(OWARE_SIZE, OWARE_INIT) == nil {
+		t.Error("Expected the idle counter to be rejected")
+	}
+}
+
+func TestValidatePlayout(t *testing.T) {
+	rng := rand.New(rand.NewSource(2671))
+	for _, rules := range []Rules{
+		{},
+		{Capture: CAPTURE_ALWAYS},
+		{Capture: CAPTURE_NONE},
+		{Remainder: REMAINDER_EMPTIER},
+		{NoEarlyWin: true},
+	} {
+		for _, size := range []uint{1, 3, 6, 8} {
+			for _, init := range []uint{1, 4, 8} {
+				b := MakeBoard(size, init)
+				b.SetRules(rules)
+				side := South
+				for !b.Over() {
+					move := b.Random(side, rng)
+					if !b.Sow(side, move) {
+						side = !side
+					}
+					if err := b.Validate(size, init); err != nil {
+						t.Fatalf("Invalid board %s after sowing %d (%s): %s",
+							b, move, rules, err)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Machine-readable web interface
+//
+// Copyright (c) 2022  Philip Kaludercic
+//
+// This file is part of go-kgp.
+//
+// go-kgp is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License,
+// version 3, as published by the Free Software Foundation.
+//
+// go-kgp is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License, version 3, along with go-kgp. If not, see
+// <http://www.gnu.org/licenses/>
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"go-kgp"
+)
+
+type apiAgent struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiMove struct {
+	Side    string    `json:"side"`
+	Choice  uint      `json:"choice"`
+	Comment string    `json:"comment"`
+	Source  string    `json:"source"`
+	Think   int64     `json:"think"` // in milliseconds
+	Board   string    `json:"board"`
+	Played  time.Time `json:"played"`
+}
+
+type apiGame struct {
+	Id      uint64    `json:"id"`
+	Size    uint      `json:"size"`
+	Init    uint      `json:"init"`
+	Rules   string    `json:"rules"`
+	Game    string    `json:"game"`
+	Opening string    `json:"opening,omitempty"`
+	North   apiAgent  `json:"north"`
+	South   apiAgent  `json:"south"`
+	State   string    `json:"state"`
+	Expired bool      `json:"expired"`
+	Moves   []apiMove `json:"moves"`
+}
+
+func makeApiAgent(a kgp.Agent) (agent apiAgent) {
+	if a != nil && a.User() != nil {
+		agent.Id = a.User().Id
+		agent.Name = a.User().Name
+	}
+	return
+}
+
+// Describe a game and all the moves it consists of in JSON
+func (s *web) apiGame(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(path.Base(r.URL.Path))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	bg := context.Background()
+	ctx, cancel := context.WithTimeout(bg, DB_TIMEOUT)
+	defer cancel()
+
+	gc := make(chan *kgp.Game, 1)
+	mc := make(chan *kgp.Move, 4) // arbitrary
+	go s.conf.DB.QueryGame(ctx, id, gc, mc)
+
+	g := <-gc
+	if g == nil {
+		http.Error(w, "Unknown game", http.StatusNotFound)
+		return
+	}
+
+	size, init := g.Board.Type()
+	game := apiGame{
+		Id:      g.Id,
+		Size:    size,
+		Init:    init,
+		Rules:   g.Board.Rules().String(),
+		Game:    g.Board.Mancala().String(),
+		North:   makeApiAgent(g.North),
+		South:   makeApiAgent(g.South),
+		State:   g.State.String(),
+		Expired: g.Expired,
+		Moves:   []apiMove{},
+	}
+	if g.Opening != nil {
+		game.Opening = g.Opening.Board.String()
+	}
+	for m := range mc {
+		var source string
+		switch m.Source {
+		case kgp.CHOSEN:
+			source = "chosen"
+		case kgp.AUTOMATIC:
+			source = "automatic"
+		case kgp.FALLBACK:
+			source = "fallback"
+		}
+
+		game.Moves = append(game.Moves, apiMove{
+			Side:    g.Side(m.Agent).String(),
+			Choice:  m.Choice,
+			Comment: m.Comment,
+			Source:  source,
+			Think:   m.Think.Milliseconds(),
+			Board:   m.State.String(),
+			Played:  m.Stamp,
+		})
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(game)
+	if err != nil {
+		s.conf.Log.Print(err)
+	}
+}
@@ -21,6 +21,7 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -39,14 +40,17 @@ const about = `<p>This is a practice server for the AI1 Kalah Tournament.</p>`
 type web struct {
 	conf *conf.Conf
 	mux  *http.ServeMux
+	srv  *http.Server
 }
 
 func (s *web) listen() {
 	addr := fmt.Sprintf(":%d", s.conf.WebPort)
 	s.conf.Debug.Printf("Listening via HTTP on %s", addr)
 
-	err := http.ListenAndServe(addr, s.mux)
-	if err != nil {
+	s.srv.Addr = addr
+	s.srv.Handler = s.mux
+	err := s.srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.conf.Log.Print(err)
 	}
 }
@@ -141,7 +145,9 @@ func (s *web) Start() {
 	s.mux.HandleFunc("/query", s.query)
 	s.mux.HandleFunc("/agents", s.showAgents)
 	s.mux.HandleFunc("/agent/", s.showAgent)
+	s.mux.HandleFunc("/leaderboard/", s.showLeaderboard)
 	s.mux.HandleFunc("/game/", s.showGame)
+	s.mux.HandleFunc("/api/game/", s.apiGame)
 	s.mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "User-agent: *\nDisallow: /")
 	})
@@ -170,6 +176,22 @@ func (s *web) Start() {
 		funcs["hasgraph"] = func() bool { return false }
 	}
 
+	// Describe the data retention policy
+	funcs["retention"] = func() string {
+		days := "a day"
+		if s.conf.RetentionDays != 1 {
+			days = fmt.Sprintf("%d days", s.conf.RetentionDays)
+		}
+		switch s.conf.Retention {
+		case conf.RETAIN_DAYS:
+			return "all games are deleted after " + days
+		case conf.RETAIN_PRACTICE:
+			return "all practice games are deleted after " + days
+		default:
+			return ""
+		}
+	}
+
 	// Install the WebSocket handler
 	if s.conf.WebSocket {
 		s.conf.Debug.Print("Accepting websocket connections on /socket")
@@ -197,8 +219,16 @@ func (s *web) Start() {
 	s.listen()
 }
 
-// The web server can shut down immediately
-func (*web) Shutdown() {}
+// Stop accepting requests and wait for active requests to finish
+func (s *web) Shutdown() {
+	bg := context.Background()
+	ctx, cancel := context.WithTimeout(bg, DB_TIMEOUT)
+	defer cancel()
+
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.conf.Log.Print(err)
+	}
+}
 
 func (*web) String() string { return "Web Server" }
 
@@ -207,5 +237,5 @@ func Prepare(conf *conf.Conf) {
 		return
 	}
 
-	conf.Register(&web{conf: conf})
+	conf.Register(&web{conf: conf, srv: &http.Server{}})
 }
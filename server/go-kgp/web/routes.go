@@ -25,9 +25,11 @@ import (
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"go-kgp"
+	"go-kgp/conf"
 )
 
 const DB_TIMEOUT = 20 * time.Second // arbitrary choice
@@ -150,6 +152,70 @@ func (s *web) showAgents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Generate a leaderboard for a game and board configuration
+//
+// The path has the form /leaderboard/GAME/SIZExINIT.  If the board
+// configuration is omitted, the first configured board is used.
+func (s *web) showLeaderboard(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/leaderboard/"), "/")
+	if len(parts) > 2 {
+		http.Error(w, "Invalid path", http.StatusNotFound)
+		return
+	}
+	m, err := kgp.ParseMancala(parts[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var boards []conf.Board
+	if m == kgp.OWARE {
+		boards = []conf.Board{{Size: kgp.OWARE_SIZE, Init: kgp.OWARE_INIT}}
+	} else {
+		boards = s.conf.Boards()
+	}
+	board := boards[0]
+	if len(parts) == 2 && parts[1] != "" {
+		bs, err := conf.ParseBoards(parts[1])
+		if err != nil || len(bs) != 1 {
+			http.Error(w, "Invalid board configuration", http.StatusNotFound)
+			return
+		}
+		board = bs[0]
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	bg := context.Background()
+	ctx, cancel := context.WithTimeout(bg, DB_TIMEOUT)
+	defer cancel()
+
+	sc := make(chan *kgp.Standing)
+	go s.conf.DB.QueryLeaderboard(ctx, m, board.Size, board.Init, sc, page-1)
+
+	title := "Kalah"
+	if m == kgp.OWARE {
+		title = "Oware"
+	}
+
+	w.Header().Add("Content-Type", "text/html")
+	w.Header().Add("Cache-Control", "max-age=60")
+	err = tmpl.ExecuteTemplate(w, "leaderboard.tmpl", struct {
+		Title     string
+		Game      string
+		Board     conf.Board
+		Boards    []conf.Board
+		Standings chan *kgp.Standing
+		Page      int
+	}{title, m.String(), board, boards, sc, page})
+	if err != nil {
+		s.conf.Log.Print(err)
+	}
+}
+
 // Generate a website to display a game
 func (s *web) showGame(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(path.Base(r.URL.Path))
@@ -50,6 +50,9 @@ var (
 		"dec": func(i int) int {
 			return i - 1
 		},
+		"percent": func(f float64) string {
+			return fmt.Sprintf("%.1f%%", 100*f)
+		},
 		"timefmt": func(t time.Time) string {
 			s := time.Since(t).Round(time.Second)
 			switch {
@@ -115,6 +118,17 @@ var (
 
 			return template.HTML(msg)
 		},
+		"think": func(m *kgp.Move) template.HTML {
+			switch m.Source {
+			case kgp.AUTOMATIC:
+				return `<em>automatic</em>`
+			case kgp.FALLBACK:
+				return template.HTML(fmt.Sprintf(`%s <span class="timeout">timeout</span>`,
+					m.Think.Round(time.Millisecond)))
+			default:
+				return template.HTML(m.Think.Round(time.Millisecond).String())
+			}
+		},
 		"now": func() string {
 			return time.Now().Format(time.RFC3339)
 		},
@@ -190,6 +204,9 @@ var (
 		},
 		"board": func(b *kgp.Board) string {
 			size, init := b.Type()
+			if b.Mancala() == kgp.OWARE {
+				return fmt.Sprintf("(%d, %d) Oware", size, init)
+			}
 			return fmt.Sprintf("(%d, %d)", size, init)
 		},
 		"draw": func(m *kgp.Move, g *kgp.Game) template.HTML {
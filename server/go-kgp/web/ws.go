@@ -21,58 +21,15 @@
 package web
 
 import (
-	"io"
 	"net/http"
 
+	"go-kgp/client"
 	"go-kgp/conf"
 	"go-kgp/proto"
 
 	"github.com/gorilla/websocket"
 )
 
-// adapted from https://github.com/gorilla/websocket/issues/282
-
-// wsrwc is a read-write-closer using websockets
-type wsrwc struct {
-	*websocket.Conn
-	r io.Reader
-}
-
-// Convert a write call to a Websocket message
-func (c *wsrwc) Write(p []byte) (int, error) {
-	err := c.WriteMessage(websocket.TextMessage, p)
-	if err != nil {
-		return 0, err
-	}
-	return len(p), nil
-}
-
-// Convert a read call into a Websocket query
-func (c *wsrwc) Read(p []byte) (int, error) {
-	for {
-		if c.r == nil {
-			// Advance to next message.
-			var err error
-			_, c.r, err = c.NextReader()
-			if err != nil {
-				return 0, err
-			}
-		}
-		n, err := c.r.Read(p)
-		if err == io.EOF {
-			// At end of message.
-			c.r = nil
-			if n > 0 {
-				return n, nil
-			} else {
-				// No data read, continue to next message.
-				continue
-			}
-		}
-		return n, err
-	}
-}
-
 // Upgrade a HTTP connection to a WebSocket and handle it
 func upgrader(conf *conf.Conf) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -88,6 +45,6 @@ func upgrader(conf *conf.Conf) http.HandlerFunc {
 		}
 
 		conf.Debug.Printf("New connection from %s", conn.RemoteAddr())
-		proto.MakeClient(&wsrwc{Conn: conn}, conf)
+		proto.MakeClient(client.WebSocket(conn), conf)
 	}
 }